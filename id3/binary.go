@@ -0,0 +1,184 @@
+package id3
+
+import (
+	"math"
+	"math/bits"
+	"reflect"
+	"sort"
+)
+
+// Training data whose features all have domain {0,1}, held as bitsets over the instance indexes. A node's
+// instances are then a mask, and bucketing them by a feature is a couple of bitwise operations rather than map
+// writes and instance clones.
+type binaryDataSet struct {
+	insts        []*Instance
+	featureNames []string
+	featureBits  [][]uint64 // Bit i is set when instance i has value 1 for the feature
+	targets      []Target
+	targetBits   [][]uint64 // Bit i is set when instance i has the target
+}
+
+// Builds the bitset form of a dataset for training with information gain, or returns nil when the fast path
+// doesn't apply: bf is some other BestFeatureFunc, a feature value isn't 0 or 1, instances have different
+// features, or instances are weighted.
+func newBinaryDataSet(ds ClassifiedDataSet, bf BestFeatureFunc) *binaryDataSet {
+	if len(ds.Instances) == 0 || bf != nil && reflect.ValueOf(bf).Pointer() != reflect.ValueOf(BestFeatureInformationGain).Pointer() {
+		return nil
+	}
+	words := (len(ds.Instances) + 63) / 64
+	b := &binaryDataSet{insts: ds.Instances}
+	for featureName := range ds.Instances[0].FeatureValues {
+		b.featureNames = append(b.featureNames, featureName)
+	}
+	sort.Strings(b.featureNames)
+	featureIndex := make(map[string]int, len(b.featureNames))
+	for j, featureName := range b.featureNames {
+		featureIndex[featureName] = j
+		b.featureBits = append(b.featureBits, make([]uint64, words))
+	}
+
+	targetIndex := make(map[Target]int)
+	for i, inst := range ds.Instances {
+		if len(inst.FeatureValues) != len(b.featureNames) || inst.weight() != 1 {
+			return nil
+		}
+		for featureName, value := range inst.FeatureValues {
+			j, ok := featureIndex[featureName]
+			if !ok || value > 1 {
+				return nil
+			} else if value == 1 {
+				b.featureBits[j][i/64] |= 1 << (i % 64)
+			}
+		}
+		k, ok := targetIndex[inst.TargetValue]
+		if !ok {
+			k = len(b.targets)
+			targetIndex[inst.TargetValue] = k
+			b.targets, b.targetBits = append(b.targets, inst.TargetValue), append(b.targetBits, make([]uint64, words))
+		}
+		b.targetBits[k][i/64] |= 1 << (i % 64)
+	}
+	return b
+}
+
+// Trains the same tree as limitedTrain with information gain would for the instances in mask. Features marked in
+// removed have already been split on above this node.
+func (b *binaryDataSet) train(mask []uint64, removed []bool, opts Options, depth int) *Decision {
+	dtree := &Decision{distribution: make(map[Target]float64, len(b.targets))}
+	for k, targetBits := range b.targetBits {
+		if targetCount := popCountAnd(mask, targetBits); targetCount > 0 {
+			dtree.distribution[b.targets[k]] = float64(targetCount)
+		}
+	}
+	count := popCount(mask)
+	if opts.MaxDepth > 0 && depth >= opts.MaxDepth || // Depth bound has been reached
+		count < opts.MinSamplesSplit { // Too few instances to split
+		dtree.outputValue, dtree.isOutput = mostPopularTarget(b.instances(mask)), true
+		return dtree
+	}
+
+	// Mirrors BestFeatureInformationGain
+	nodeEntropy := b.entropy(mask, count)
+	bestFeature := -1
+	for j := range b.featureNames {
+		if removed[j] {
+			continue
+		}
+		infoGain := nodeEntropy
+		for _, subset := range [][]uint64{andNot(mask, b.featureBits[j]), and(mask, b.featureBits[j])} {
+			if subsetCount := popCount(subset); subsetCount > 0 {
+				infoGain -= float64(subsetCount) / float64(count) * b.entropy(subset, subsetCount)
+			}
+		}
+		if infoGain > dtree.gain { // Names are sorted, so ties already go to the smallest
+			dtree.gain, bestFeature = infoGain, j
+		}
+	}
+	if bestFeature < 0 { // No features left
+		dtree.outputValue, dtree.isOutput = mostPopularTarget(b.instances(mask)), true
+		return dtree
+	} else if opts.MinImpurityDecreaseFraction > 0 && dtree.gain/nodeEntropy < opts.MinImpurityDecreaseFraction ||
+		opts.MinGain > 0 && dtree.gain < opts.MinGain {
+		dtree.outputValue, dtree.isOutput, dtree.gain = mostPopularTarget(b.instances(mask)), true, 0
+		return dtree
+	}
+
+	dtree.featureName = b.featureNames[bestFeature]
+	dtree.nextDecisions = make(map[Feature]*Decision, 2)
+	buckets := [2][]uint64{andNot(mask, b.featureBits[bestFeature]), and(mask, b.featureBits[bestFeature])}
+	removed[bestFeature] = true
+	for featureValue, bucket := range buckets {
+		if popCount(bucket) > 0 {
+			dtree.nextDecisions[Feature(featureValue)] = b.train(bucket, removed, opts, depth+1)
+		}
+	}
+	removed[bestFeature] = false
+	return dtree
+}
+
+// Calculates entropy of the target values of the count instances in mask.
+func (b *binaryDataSet) entropy(mask []uint64, count int) float64 {
+	H := 0.0
+	for _, targetBits := range b.targetBits {
+		if targetCount := popCountAnd(mask, targetBits); targetCount > 0 {
+			pI := float64(targetCount) / float64(count)
+			H += pI * math.Log2(pI)
+		}
+	}
+	return -H
+}
+
+// Lists the instances in mask in their original order.
+func (b *binaryDataSet) instances(mask []uint64) []*Instance {
+	insts := make([]*Instance, 0, popCount(mask))
+	for i, inst := range b.insts {
+		if mask[i/64]&(1<<(i%64)) != 0 {
+			insts = append(insts, inst)
+		}
+	}
+	return insts
+}
+
+// A mask selecting the first n instances.
+func fullMask(n int) []uint64 {
+	mask := make([]uint64, (n+63)/64)
+	for i := range mask {
+		mask[i] = ^uint64(0)
+	}
+	if n%64 != 0 {
+		mask[len(mask)-1] = 1<<(n%64) - 1
+	}
+	return mask
+}
+
+func and(a, b []uint64) []uint64 {
+	out := make([]uint64, len(a))
+	for i := range a {
+		out[i] = a[i] & b[i]
+	}
+	return out
+}
+
+func andNot(a, b []uint64) []uint64 {
+	out := make([]uint64, len(a))
+	for i := range a {
+		out[i] = a[i] &^ b[i]
+	}
+	return out
+}
+
+func popCountAnd(a, b []uint64) int {
+	count := 0
+	for i := range a {
+		count += bits.OnesCount64(a[i] & b[i])
+	}
+	return count
+}
+
+func popCount(a []uint64) int {
+	count := 0
+	for _, word := range a {
+		count += bits.OnesCount64(word)
+	}
+	return count
+}
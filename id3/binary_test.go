@@ -0,0 +1,93 @@
+package id3
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// A dataset of binary features where the target depends on the first three, with some noise.
+func randomBinaryDataSet(n, features int, seed int64) ClassifiedDataSet {
+	rng := rand.New(rand.NewSource(seed))
+	ds := ClassifiedDataSet{}
+	for i := 0; i < n; i++ {
+		inst := &Instance{FeatureValues: make(map[string]Feature, features)}
+		for j := 0; j < features; j++ {
+			inst.FeatureValues[string(rune('a'+j))] = Feature(rng.Intn(2))
+		}
+		inst.TargetValue = Target(inst.FeatureValues["a"] == 1 && inst.FeatureValues["b"] == 1 || inst.FeatureValues["c"] == 1) != Target(rng.Intn(10) == 0)
+		ds.Instances = append(ds.Instances, inst)
+	}
+	return ds
+}
+
+// Trains sequentially with the general map-based path regardless of the dataset.
+func trainGeneral(ds ClassifiedDataSet, opts Options) (*Decision, error) {
+	return limitedTrain(ds, opts, 0, false)
+}
+
+// Checks two trees have the same shape, features, outputs and (up to rounding) gains.
+func sameTree(a, b *Decision) bool {
+	if a.isOutput != b.isOutput || a.featureName != b.featureName || a.outputValue != b.outputValue ||
+		math.Abs(a.gain-b.gain) > 1e-12 || !reflect.DeepEqual(a.distribution, b.distribution) ||
+		len(a.nextDecisions) != len(b.nextDecisions) {
+		return false
+	}
+	for featureValue, subtree := range a.nextDecisions {
+		if other, ok := b.nextDecisions[featureValue]; !ok || !sameTree(subtree, other) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestBinaryFastPath(t *testing.T) {
+	for _, ds := range []ClassifiedDataSet{randomBinaryDataSet(500, 8, 1), randomBinaryDataSet(70, 5, 2)} {
+		if newBinaryDataSet(ds, BestFeatureInformationGain) == nil {
+			t.Fatal("Expected the fast path to apply to an all-binary dataset")
+		}
+		for _, opts := range []Options{{}, {MaxDepth: 3}, {MinImpurityDecreaseFraction: 0.2}, {MinSamplesSplit: 20}, {MinGain: 0.05}} {
+			fast, err := TrainWithOptions(ds, opts)
+			if err != nil {
+				t.Fatal("Encountered tree training error", err)
+			}
+			general, err := trainGeneral(ds, opts)
+			if err != nil {
+				t.Fatal("Encountered tree training error", err)
+			}
+			if !sameTree(fast, general) || !reflect.DeepEqual(fast.String(), general.String()) {
+				t.Error("Expected identical trees with", opts, "got", fast.String(), "and", general.String())
+			}
+		}
+	}
+
+	if newBinaryDataSet(tennisDataSet(), BestFeatureInformationGain) != nil {
+		t.Error("Expected the fast path not to apply to 3-valued features")
+	}
+	if newBinaryDataSet(randomBinaryDataSet(10, 3, 1), BestFeatureGiniImpurity) != nil {
+		t.Error("Expected the fast path not to apply to other BestFeatureFuncs")
+	}
+}
+
+func BenchmarkTrainBinaryFastPath(b *testing.B) {
+	ds := randomBinaryDataSet(2000, 12, 1)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Train(ds, BestFeatureInformationGain); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTrainBinaryGeneral(b *testing.B) {
+	ds := randomBinaryDataSet(2000, 12, 1)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := trainGeneral(ds, Options{BestFeature: BestFeatureInformationGain}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
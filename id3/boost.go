@@ -0,0 +1,109 @@
+package id3
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// An ensemble of decision stumps trained by AdaBoost, which classifies by a vote weighted by each stump's accuracy.
+type BoostedModel struct {
+	stumps []*Decision
+	alphas []float64
+}
+
+// Trains a boosted model of up to rounds decision stumps. Each round trains a stump on the instances weighted so
+// that those the previous stumps got wrong count for more, and gives it a vote of the log-odds of its weighted
+// accuracy. Targets with more than two values are handled as in SAMME, which adds log(targets-1) to every vote.
+// Boosting stops early once a stump is perfect or no better than chance. A dataset with a single target gets a lone
+// leaf with a vote of 1, as SAMME's vote is undefined there. The dataset's instances aren't modified.
+func AdaBoost(ds ClassifiedDataSet, rounds int) (*BoostedModel, error) {
+	if len(ds.Instances) == 0 {
+		return nil, errors.New("no instances provided")
+	} else if rounds < 1 {
+		return nil, errors.New(fmt.Sprint("need at least one round, got ", rounds))
+	}
+	numTargets := float64(len(targetDistribution(ds.Instances)))
+	weighted := ClassifiedDataSet{Instances: make([]*Instance, len(ds.Instances))}
+	for i, inst := range ds.Instances {
+		weighted.Instances[i] = &Instance{inst.FeatureValues, inst.TargetValue, inst.weight()}
+	}
+
+	if numTargets < 2 { // log(targets-1) would make the vote -Inf
+		leaf, err := LimitedTrain(weighted, BestFeatureInformationGain, 0)
+		if err != nil {
+			return nil, err
+		}
+		return &BoostedModel{stumps: []*Decision{leaf}, alphas: []float64{1}}, nil
+	}
+
+	m := &BoostedModel{}
+	wrong := make([]bool, len(ds.Instances))
+	for round := 0; round < rounds; round++ {
+		stump, err := LimitedTrain(weighted, BestFeatureInformationGain, 1) // Only the root splits
+		if err != nil {
+			return nil, err
+		}
+		wrongWeight := 0.0
+		for i, inst := range weighted.Instances {
+			target, err := stump.Predict(inst)
+			if wrong[i] = err != nil || target != inst.TargetValue; wrong[i] {
+				wrongWeight += inst.Weight
+			}
+		}
+		stumpError := wrongWeight / totalWeight(weighted.Instances)
+		if stumpError >= 1-1/numTargets && len(m.stumps) > 0 { // No better than chance
+			break
+		}
+		alpha := math.Log((1-stumpError)/math.Max(stumpError, 1e-10)) + math.Log(numTargets-1)
+		m.stumps, m.alphas = append(m.stumps, stump), append(m.alphas, alpha)
+		if stumpError == 0 || stumpError >= 1-1/numTargets { // Reweighting can't improve on this stump
+			break
+		}
+
+		// Upweight the mistakes, then rescale so the weights average 1 and don't underflow
+		for i, inst := range weighted.Instances {
+			if wrong[i] {
+				inst.Weight *= math.Exp(alpha)
+			}
+		}
+		scale := float64(len(weighted.Instances)) / totalWeight(weighted.Instances)
+		for _, inst := range weighted.Instances {
+			inst.Weight *= scale
+		}
+	}
+	return m, nil
+}
+
+// The stumps making up the model, in the order they were trained.
+func (m *BoostedModel) Stumps() []*Decision {
+	return m.stumps
+}
+
+// Attempt to classify a provided instance of data by the weighted vote of the stumps. The classification is set in
+// the instance's TargetValue field.
+func (m *BoostedModel) Classify(inst *Instance) error {
+	target, err := m.Predict(inst)
+	if err != nil {
+		return err
+	}
+	inst.TargetValue = target
+	return nil
+}
+
+// Finds the target with the greatest weighted vote of the stumps, leaving the instance untouched. Stumps that can't
+// classify the instance abstain, and ties go to the smaller target.
+func (m *BoostedModel) Predict(inst *Instance) (Target, error) {
+	votes := make(map[Target]float64)
+	voted := false
+	for i, stump := range m.stumps {
+		if target, err := stump.Predict(inst); err == nil {
+			votes[target] += m.alphas[i]
+			voted = true
+		}
+	}
+	if !voted {
+		return nil, errors.New(fmt.Sprint("no stump could classify feature values ", inst.FeatureValues))
+	}
+	return mostVotedTarget(votes), nil
+}
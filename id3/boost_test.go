@@ -0,0 +1,84 @@
+package id3
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// The target is the majority of three binary features, which no single stump can capture but a vote of three can.
+func majorityDataSet() ClassifiedDataSet {
+	ds := ClassifiedDataSet{}
+	for i := 0; i < 8; i++ {
+		a, b, c := Feature(i&1), Feature(i>>1&1), Feature(i>>2&1)
+		ds.Instances = append(ds.Instances, &Instance{map[string]Feature{"a": a, "b": b, "c": c}, Target(a+b+c >= 2), 0})
+	}
+	return ds
+}
+
+func TestAdaBoost(t *testing.T) {
+	ds := majorityDataSet()
+	stump, err := LimitedTrain(ds, BestFeatureInformationGain, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stumpError, _ := stump.CalculateError(ds); stumpError != 0.25 {
+		t.Error("Expected a single stump to get a quarter wrong, got", stumpError)
+	}
+
+	m, err := AdaBoost(ds, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, inst := range ds.Instances {
+		if target, err := m.Predict(inst); err != nil {
+			t.Error(err)
+		} else if target != inst.TargetValue {
+			t.Error("Expected", inst.TargetValue, "for", inst.FeatureValues, "got", target)
+		}
+	}
+	splits := make(map[string]bool)
+	for _, stump := range m.Stumps() {
+		splits[stump.featureName] = true
+	}
+	if len(splits) != 3 {
+		t.Error("Expected stumps on all three features, got", splits)
+	}
+	if !reflect.DeepEqual(ds, majorityDataSet()) {
+		t.Error("Expected the instances to be left unmodified")
+	}
+
+	classified := ds.Instances[7].Clone()
+	classified.TargetValue = nil
+	if err := m.Classify(classified); err != nil || classified.TargetValue != Target(true) {
+		t.Error("Expected true, got", classified.TargetValue, err)
+	}
+	if _, err := m.Predict(&Instance{map[string]Feature{"d": 1}, nil, 0}); err == nil {
+		t.Error("Expected an error when no stump can classify")
+	}
+	if _, err := AdaBoost(ds, 0); err == nil {
+		t.Error("Expected an error with no rounds")
+	}
+	if _, err := AdaBoost(ClassifiedDataSet{}, 10); err == nil {
+		t.Error("Expected an error training on no instances")
+	}
+}
+
+func TestAdaBoostSingleTarget(t *testing.T) {
+	ds := ClassifiedDataSet{}
+	for _, inst := range majorityDataSet().Instances {
+		ds.Instances = append(ds.Instances, &Instance{inst.FeatureValues, "only", 0})
+	}
+	m, err := AdaBoost(ds, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.alphas) != 1 || m.alphas[0] <= 0 || math.IsInf(m.alphas[0], 0) {
+		t.Error("Expected a single finite, positive vote, got", m.alphas)
+	}
+	for _, inst := range ds.Instances {
+		if target, err := m.Predict(inst); err != nil || target != Target("only") {
+			t.Error("Expected only, got", target, err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package id3
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Wraps a tree with a prediction cache for workloads that see the same feature values again and again. A
+// prediction only depends on the features read along the instance's own path, so the cache is keyed on just those:
+// instances that differ only in features their path never consults share an entry, even if another branch of the
+// tree reads them. Safe for concurrent use.
+type CachedClassifier struct {
+	dtree *Decision
+	mu    sync.RWMutex
+	cache map[string]Target
+	hits  int
+}
+
+// Creates a cached classifier for a tree. The tree shouldn't be changed, e.g. by pruning, while the classifier is
+// in use.
+func NewCachedClassifier(dtree *Decision) *CachedClassifier {
+	return &CachedClassifier{dtree: dtree, cache: make(map[string]Target)}
+}
+
+// Attempt to classify a provided instance of data, from the cache when possible. The classification is set in the
+// instance's TargetValue field. Instances the tree can't classify aren't cached.
+func (c *CachedClassifier) Classify(inst *Instance) error {
+	key := c.key(inst.FeatureValues)
+	c.mu.RLock()
+	target, ok := c.cache[key]
+	c.mu.RUnlock()
+	if ok {
+		c.mu.Lock()
+		c.hits++
+		c.mu.Unlock()
+		inst.TargetValue = target
+		return nil
+	}
+
+	target, err := c.dtree.Predict(inst)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.cache[key] = target
+	c.mu.Unlock()
+	inst.TargetValue = target
+	return nil
+}
+
+// The number of distinct cache entries, i.e. distinct paths through the tree seen.
+func (c *CachedClassifier) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.cache)
+}
+
+// The number of classifications answered from the cache.
+func (c *CachedClassifier) Hits() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.hits
+}
+
+// Encodes the branches the features lead down, walking the tree as classifying them would. The walk is decided by
+// the branches taken so far, so the encoding doesn't need the feature names: equal keys mean the same nodes were
+// visited and left the same way. Values without a branch end the walk, and a missing value walks every branch.
+func (c *CachedClassifier) key(features map[string]Feature) string {
+	var b strings.Builder
+	appendKey(&b, c.dtree, features)
+	return b.String()
+}
+
+func appendKey(b *strings.Builder, node *Decision, features map[string]Feature) {
+	if node.isOutput {
+		return
+	}
+	value, ok := features[node.featureName]
+	if !ok {
+		b.WriteString("-,")
+		return
+	} else if value == FeatureMissing {
+		b.WriteString("?,")
+		for _, subtree := range node.uniqueChildren() {
+			appendKey(b, subtree, features)
+		}
+		return
+	}
+	b.WriteString(strconv.Itoa(int(node.branchKey(value))))
+	b.WriteByte(',')
+	if nextDecision, ok := node.nextDecision(value); ok {
+		appendKey(b, nextDecision, features)
+	}
+}
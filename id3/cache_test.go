@@ -0,0 +1,80 @@
+package id3
+
+import (
+	"testing"
+)
+
+func TestCachedClassifier(t *testing.T) {
+	dtree, err := Train(tennisDataSet(), BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewCachedClassifier(dtree)
+
+	// The tree never reads temp, so these only differ in an unused feature
+	a := &Instance{map[string]Feature{"outlook": 2, "temp": 0, "humidity": 1, "wind": 0}, nil, 1}
+	b := &Instance{map[string]Feature{"outlook": 2, "temp": 2, "humidity": 1, "wind": 0}, nil, 1}
+	for _, inst := range []*Instance{a, b} {
+		if err := c.Classify(inst); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c.Len() != 1 || c.Hits() != 1 {
+		t.Error("Expected both instances to share one cache entry, got", c.Len(), "entries and", c.Hits(), "hits")
+	}
+	if a.TargetValue != Target(false) || b.TargetValue != a.TargetValue {
+		t.Error("Expected both instances to be classified false, got", a.TargetValue, b.TargetValue)
+	}
+
+	// Overcast instances never reach the splits on humidity and wind, though sunny and rainy ones do
+	overcastA := &Instance{map[string]Feature{"outlook": 1, "temp": 0, "humidity": 0, "wind": 0}, nil, 1}
+	overcastB := &Instance{map[string]Feature{"outlook": 1, "temp": 0, "humidity": 1, "wind": 1}, nil, 1}
+	for _, inst := range []*Instance{overcastA, overcastB} {
+		if err := c.Classify(inst); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c.Len() != 2 || c.Hits() != 2 {
+		t.Error("Expected the overcast instances to share one more entry, got", c.Len(), "entries and", c.Hits(), "hits")
+	}
+	if overcastA.TargetValue != Target(true) || overcastB.TargetValue != Target(true) {
+		t.Error("Expected both overcast instances to be classified true, got", overcastA.TargetValue, overcastB.TargetValue)
+	}
+
+	for _, inst := range tennisDataSet().Instances {
+		classified := inst.Clone()
+		if err := c.Classify(classified); err != nil {
+			t.Error(err)
+		} else if classified.TargetValue != inst.TargetValue {
+			t.Error("Expected", inst.TargetValue, "got", classified.TargetValue, "for", inst.FeatureValues)
+		}
+	}
+	if err := c.Classify(&Instance{map[string]Feature{"outlook": 7}, nil, 1}); err == nil || c.Len() > 14 {
+		t.Error("Expected an unclassifiable instance to error without being cached")
+	}
+}
+
+func TestCachedClassifierMissingValues(t *testing.T) {
+	dtree, err := Train(tennisDataSet(), BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewCachedClassifier(dtree)
+	// The vote across the outlook branches has to match the uncached tree's, from the tree and from the cache
+	inst := &Instance{map[string]Feature{"outlook": FeatureMissing, "humidity": 1, "wind": 1}, nil, 1}
+	expected, err := dtree.Predict(inst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		classified := inst.Clone()
+		if err := c.Classify(classified); err != nil {
+			t.Fatal(err)
+		} else if classified.TargetValue != expected {
+			t.Error("Expected", expected, "got", classified.TargetValue)
+		}
+	}
+	if c.Len() != 1 || c.Hits() != 1 {
+		t.Error("Expected the missing value to be cached once, got", c.Len(), "entries and", c.Hits(), "hits")
+	}
+}
@@ -0,0 +1,96 @@
+package id3
+
+import (
+	"math"
+	"sort"
+)
+
+// Tests the split made at the node for independence from the target with Pearson's chi-squared test, over the
+// weighted counts of each target down each branch, and returns the p-value: the chance of an association at least
+// as strong if the feature told nothing about the target. Instances missing the feature are left out. A split
+// with a single branch or a node with a single target has no evidence of association, so a p-value of 1.
+func (dtree *Decision) chiSquarePValue(insts []*Instance) float64 {
+	observed := make(map[Feature]map[Target]float64)
+	branchTotals, targetTotals := make(map[Feature]float64), make(map[Target]float64)
+	total := 0.0
+	for _, inst := range knownInstances(insts, dtree.featureName) {
+		key := dtree.branchKey(inst.FeatureValues[dtree.featureName])
+		if observed[key] == nil {
+			observed[key] = make(map[Target]float64)
+		}
+		observed[key][inst.TargetValue] += inst.weight()
+		branchTotals[key] += inst.weight()
+		targetTotals[inst.TargetValue] += inst.weight()
+		total += inst.weight()
+	}
+	degreesOfFreedom := (len(branchTotals) - 1) * (len(targetTotals) - 1)
+	if degreesOfFreedom == 0 {
+		return 1
+	}
+
+	// Summed in order of branch and target, so rounding is the same every time
+	keys := make([]Feature, 0, len(branchTotals))
+	for key := range branchTotals {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	targets := make([]Target, 0, len(targetTotals))
+	for target := range targetTotals {
+		targets = append(targets, target)
+	}
+	sort.Slice(targets, func(i, j int) bool { return targetLess(targets[i], targets[j]) })
+	statistic := 0.0
+	for _, key := range keys {
+		for _, target := range targets {
+			expected := branchTotals[key] * targetTotals[target] / total
+			statistic += math.Pow(observed[key][target]-expected, 2) / expected
+		}
+	}
+	return chiSquareSurvival(statistic, degreesOfFreedom)
+}
+
+// Calculates the probability of a chi-squared variable with the given degrees of freedom being at least x.
+func chiSquareSurvival(x float64, degreesOfFreedom int) float64 {
+	return upperIncompleteGamma(float64(degreesOfFreedom)/2, x/2)
+}
+
+// Calculates the regularized upper incomplete gamma function Q(a, x), by its power series when x is small relative
+// to a and by its continued fraction, evaluated with Lentz's method, otherwise.
+func upperIncompleteGamma(a, x float64) float64 {
+	const epsilon, tiny, maxIterations = 1e-15, 1e-300, 500
+	if x <= 0 {
+		return 1
+	}
+	logGammaA, _ := math.Lgamma(a)
+	prefix := math.Exp(a*math.Log(x) - x - logGammaA)
+	if x < a+1 {
+		term := 1 / a
+		sum := term
+		for n := 1; n < maxIterations && term > sum*epsilon; n++ {
+			term *= x / (a + float64(n))
+			sum += term
+		}
+		return math.Max(1-prefix*sum, 0)
+	}
+
+	b := x + 1 - a
+	c, d := 1/tiny, 1/b
+	fraction := d
+	for n := 1; n < maxIterations; n++ {
+		an := -float64(n) * (float64(n) - a)
+		b += 2
+		if d = an*d + b; math.Abs(d) < tiny {
+			d = tiny
+		}
+		if c = b + an/c; math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		fraction *= delta
+		if math.Abs(delta-1) < epsilon {
+			break
+		}
+	}
+	return prefix * fraction
+}
@@ -0,0 +1,71 @@
+package id3
+
+import (
+	"math"
+	"testing"
+)
+
+func TestChiSquareSurvival(t *testing.T) {
+	for _, tc := range []struct {
+		x                float64
+		degreesOfFreedom int
+		p                float64
+	}{
+		{3.841, 1, 0.05},
+		{6.635, 1, 0.01},
+		{5.991, 2, 0.05},
+		{10, 2, math.Exp(-5)}, // Exact for two degrees of freedom
+		{9.488, 4, 0.05},
+		{0.711, 4, 0.95},
+		{37.566, 20, 0.01},
+		{0, 3, 1},
+	} {
+		if p := chiSquareSurvival(tc.x, tc.degreesOfFreedom); math.Abs(p-tc.p) > 1e-4 {
+			t.Error("Expected p", tc.p, "for", tc.x, "with", tc.degreesOfFreedom, "degrees of freedom, got", p)
+		}
+	}
+}
+
+func TestChiSquareAlpha(t *testing.T) {
+	// Outlook has a p-value of about 0.17 at the root, and wind and humidity about 0.025 below it
+	ds := tennisDataSet()
+	outlook := &Decision{featureName: "outlook"}
+	if p := outlook.chiSquarePValue(ds.Instances); math.Abs(p-0.1698) > 1e-3 {
+		t.Error("Expected a p-value of about 0.17 for outlook, got", p)
+	}
+	full, err := Train(ds, BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		alpha float64
+		full  bool
+	}{
+		{0.2, true},
+		{0.05, false},
+	} {
+		dtree, err := TrainWithOptions(ds, Options{ChiSquareAlpha: tc.alpha})
+		if err != nil {
+			t.Fatal(err)
+		} else if dtree.Equal(full) != tc.full || !tc.full && (!dtree.isOutput || dtree.outputValue != Target(true)) {
+			t.Error("Expected the full tree", tc.full, "at alpha", tc.alpha, "got", dtree.String())
+		}
+	}
+
+	noisy := randomBinaryDataSet(1000, 8, 1)
+	unpruned, err := Train(noisy, BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dtree, err := TrainWithOptions(noisy, Options{ChiSquareAlpha: 0.01})
+	if err != nil {
+		t.Fatal(err)
+	} else if dtree.isOutput || dtree.NodeCount() >= unpruned.NodeCount() {
+		t.Error("Expected fewer than", unpruned.NodeCount(), "nodes, got", dtree.NodeCount())
+	}
+
+	single := &Decision{featureName: "a"}
+	if p := single.chiSquarePValue(ds.Instances[:1]); p != 1 {
+		t.Error("Expected no evidence from a single instance, got", p)
+	}
+}
@@ -0,0 +1,110 @@
+package id3
+
+import (
+	"math"
+	"sort"
+)
+
+// Picks the split for a node: BestFeature's pick among the categorical features, unless a threshold split on one
+// of the continuous features has greater information gain. Ties go to the categorical feature, then to the
+// smallest continuous feature name.
+func chooseSplit(ds ClassifiedDataSet, bf BestFeatureFunc, continuous map[string]bool) (featureName string, isContinuous bool, threshold Feature) {
+	if len(continuous) == 0 {
+		return bf(ds), false, 0
+	}
+
+	// BestFeature only gets to see the categorical features
+	categorical := ClassifiedDataSet{Instances: make([]*Instance, len(ds.Instances))}
+	var continuousNames []string
+	for featureName := range ds.Instances[0].FeatureValues {
+		if continuous[featureName] {
+			continuousNames = append(continuousNames, featureName)
+		}
+	}
+	sort.Strings(continuousNames)
+	for i, inst := range ds.Instances {
+		categorical.Instances[i] = &Instance{make(map[string]Feature, len(inst.FeatureValues)), inst.TargetValue, inst.Weight}
+		for featureName, value := range inst.FeatureValues {
+			if !continuous[featureName] {
+				categorical.Instances[i].FeatureValues[featureName] = value
+			}
+		}
+	}
+	greatestGain := 0.0
+	if featureName = bf(categorical); featureName != "" {
+		greatestGain = InformationGain(categorical, featureName)
+	}
+
+	for _, continuousName := range continuousNames {
+		if t, gain := bestThreshold(ds, continuousName); gain > greatestGain {
+			featureName, isContinuous, threshold, greatestGain = continuousName, true, t, gain
+		}
+	}
+	return featureName, isContinuous, threshold
+}
+
+// Finds the threshold of a continuous feature whose binary split has the greatest information gain. Candidate
+// thresholds lie halfway between consecutive distinct values, rounded down, so unseen values in between are split
+// evenly. Ties go to the smallest threshold, and a feature with a single value has no threshold with any gain.
+func bestThreshold(ds ClassifiedDataSet, featureName string) (threshold Feature, gain float64) {
+	insts := append([]*Instance{}, knownInstances(ds.Instances, featureName)...)
+	sort.SliceStable(insts, func(i, j int) bool { return insts[i].FeatureValues[featureName] < insts[j].FeatureValues[featureName] })
+
+	parentEntropy := Entropy(insts)
+	below, above := make(map[Target]float64), targetDistribution(insts)
+	n, nBelow := totalWeight(insts), 0.0
+	for i := 0; i < len(insts)-1; i++ {
+		below[insts[i].TargetValue] += insts[i].weight()
+		above[insts[i].TargetValue] -= insts[i].weight()
+		nBelow += insts[i].weight()
+		lower, upper := insts[i].FeatureValues[featureName], insts[i+1].FeatureValues[featureName]
+		if lower == upper {
+			continue
+		}
+		candidateGain := parentEntropy - nBelow/n*countsEntropy(below) - (n-nBelow)/n*countsEntropy(above)
+		if candidateGain > gain {
+			threshold, gain = lower+(upper-lower)/2, candidateGain
+		}
+	}
+	return threshold, n / totalWeight(ds.Instances) * gain // Scaled by the fraction with a value, as in InformationGain
+}
+
+// Calculates the information gain of a node's split, whether categorical or by threshold.
+func (dtree *Decision) splitGain(ds ClassifiedDataSet) float64 {
+	if !dtree.continuous {
+		return InformationGain(ds, dtree.featureName)
+	}
+	known := knownInstances(ds.Instances, dtree.featureName)
+	buckets := make([][]*Instance, 2)
+	for _, inst := range known {
+		key := dtree.branchKey(inst.FeatureValues[dtree.featureName])
+		buckets[key] = append(buckets[key], inst)
+	}
+	gain := Entropy(known)
+	for _, bucket := range buckets {
+		if len(bucket) > 0 {
+			gain -= totalWeight(bucket) / totalWeight(known) * Entropy(bucket)
+		}
+	}
+	return totalWeight(known) / totalWeight(ds.Instances) * gain
+}
+
+// Calculates the entropy of a target distribution given as (weighted) counts, in order of target so rounding is
+// the same every time.
+func countsEntropy(counts map[Target]float64) float64 {
+	total := 0.0
+	targets := make([]Target, 0, len(counts))
+	for target, count := range counts {
+		total += count
+		targets = append(targets, target)
+	}
+	sort.Slice(targets, func(i, j int) bool { return targetLess(targets[i], targets[j]) })
+	H := 0.0
+	for _, target := range targets {
+		if count := counts[target]; count > 0 {
+			pI := count / total
+			H += pI * math.Log2(pI)
+		}
+	}
+	return -H
+}
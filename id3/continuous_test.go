@@ -0,0 +1,64 @@
+package id3
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// Even ages only, so odd ages are never seen in training. The target is true for ages in (20, 60].
+func agesDataSet() ClassifiedDataSet {
+	ds := ClassifiedDataSet{}
+	for age := 0; age < 100; age += 2 {
+		ds.Instances = append(ds.Instances, &Instance{map[string]Feature{"age": Feature(age), "noise": Feature(age / 2 % 3)}, age > 20 && age <= 60, 1})
+	}
+	return ds
+}
+
+func TestContinuousFeatures(t *testing.T) {
+	ds := agesDataSet()
+	dtree, err := TrainWithOptions(ds, Options{ContinuousFeatures: map[string]bool{"age": true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if consistent, err := dtree.IsConsistent(ds); err != nil || !consistent {
+		t.Error("Expected a consistent tree, got", dtree.String(), err)
+	}
+	for age, expected := range map[Feature]Target{11: false, 31: true, 51: true, 71: false, 99: false} {
+		inst := &Instance{map[string]Feature{"age": age, "noise": 0}, nil, 1}
+		if err := dtree.Classify(inst); err != nil {
+			t.Error(err)
+		} else if inst.TargetValue != expected {
+			t.Error("Expected", expected, "for unseen age", age, "got", inst.TargetValue)
+		}
+	}
+	expectedTree := []string{"age[<=61] ==> age[<=21] ==> false", "age[<=61] ==> age[>21] ==> true", "age[>61] ==> false"}
+	if treeStr := dtree.String(); !reflect.DeepEqual(treeStr, expectedTree) {
+		t.Errorf("Expected %#v got %#v\n", expectedTree, treeStr)
+	}
+
+	encoded, err := json.Marshal(dtree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded := &Decision{}
+	if err := json.Unmarshal(encoded, reloaded); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(reloaded, dtree) {
+		t.Error("Expected the threshold tree to round trip, got", reloaded.String())
+	}
+	rules := dtree.ToRuleList()
+	for age := Feature(0); age < 100; age++ {
+		treeInst, listInst := &Instance{map[string]Feature{"age": age}, nil, 1}, &Instance{map[string]Feature{"age": age}, nil, 1}
+		if dtree.Classify(treeInst) != nil || rules.Classify(listInst) != nil || treeInst.TargetValue != listInst.TargetValue {
+			t.Error("Rule list disagrees with tree on age", age)
+		}
+	}
+
+	categorical, err := Train(ds, BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := categorical.Classify(&Instance{map[string]Feature{"age": 31, "noise": 0}, nil, 1}); err == nil {
+		t.Error("Expected a categorical tree to fail on an unseen age")
+	}
+}
@@ -0,0 +1,314 @@
+package id3
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Checks that two datasets share the same feature names so their instances can be mixed. The datasets are returned
+// unchanged when they do, ready for Concat. An empty dataset has no schema and aligns with anything.
+func AlignDataSets(a, b ClassifiedDataSet) (ClassifiedDataSet, ClassifiedDataSet, error) {
+	if len(a.Instances) == 0 || len(b.Instances) == 0 {
+		return a, b, nil
+	}
+	aSchema, bSchema := featureSchema(a), featureSchema(b)
+	var onlyA, onlyB []string
+	for featureName := range aSchema {
+		if !bSchema[featureName] {
+			onlyA = append(onlyA, featureName)
+		}
+	}
+	for featureName := range bSchema {
+		if !aSchema[featureName] {
+			onlyB = append(onlyB, featureName)
+		}
+	}
+	if len(onlyA) > 0 || len(onlyB) > 0 {
+		sort.Strings(onlyA)
+		sort.Strings(onlyB)
+		return a, b, errors.New(fmt.Sprint("feature schemas differ: only in first ", onlyA, ", only in second ", onlyB))
+	}
+	return a, b, nil
+}
+
+// Creates a new dataset holding the instances of ds followed by those of other, as long as their feature names
+// align. The instances themselves are shared, not cloned.
+func (ds ClassifiedDataSet) Concat(other ClassifiedDataSet) (ClassifiedDataSet, error) {
+	if _, _, err := AlignDataSets(ds, other); err != nil {
+		return ClassifiedDataSet{}, err
+	}
+	instances := make([]*Instance, 0, len(ds.Instances)+len(other.Instances))
+	instances = append(instances, ds.Instances...)
+	return ClassifiedDataSet{Instances: append(instances, other.Instances...)}, nil
+}
+
+// Appends the instances of other to the dataset in place, as long as their feature names align. On error the
+// dataset is left unchanged. The instances themselves are shared, not cloned.
+func (ds *ClassifiedDataSet) Append(other ClassifiedDataSet) error {
+	if _, _, err := AlignDataSets(*ds, other); err != nil {
+		return err
+	}
+	ds.Instances = append(ds.Instances, other.Instances...)
+	return nil
+}
+
+// Creates a new dataset holding the instances of all the datasets in order, such as ones loaded from several
+// files, as long as every one's feature names align with those before it. The error names the first dataset that
+// doesn't align.
+func ConcatDataSets(datasets ...ClassifiedDataSet) (ClassifiedDataSet, error) {
+	concatenated := ClassifiedDataSet{}
+	for i, ds := range datasets {
+		var err error
+		if concatenated, err = concatenated.Concat(ds); err != nil {
+			return ClassifiedDataSet{}, errors.New(fmt.Sprint("dataset ", i, ": ", err))
+		}
+	}
+	return concatenated, nil
+}
+
+// An overview of a dataset to check before training it.
+type DataSetSummary struct {
+	Instances      int
+	DistinctValues map[string]int     // Number of different values of each feature, not counting FeatureMissing
+	TargetCounts   map[Target]float64 // Weight of the instances with each target
+	TargetEntropy  float64            // Entropy in bits of the target distribution, 0 for a single target
+}
+
+// Summarizes the dataset in a single pass over its instances. Features with a number of distinct values close to the
+// number of instances, or near the capacity of Feature, won't split usefully, and target counts far apart warn of
+// an imbalance that may call for ForestOptions.ClassWeights or instance weights.
+func (ds ClassifiedDataSet) Summary() DataSetSummary {
+	summary := DataSetSummary{
+		Instances:      len(ds.Instances),
+		DistinctValues: make(map[string]int),
+		TargetCounts:   make(map[Target]float64),
+	}
+	seen := make(map[string]map[Feature]bool)
+	for _, inst := range ds.Instances {
+		for featureName, featureValue := range inst.FeatureValues {
+			if seen[featureName] == nil {
+				seen[featureName] = make(map[Feature]bool)
+				summary.DistinctValues[featureName] = 0
+			}
+			if featureValue != FeatureMissing && !seen[featureName][featureValue] {
+				seen[featureName][featureValue] = true
+				summary.DistinctValues[featureName]++
+			}
+		}
+		summary.TargetCounts[inst.TargetValue] += inst.weight()
+	}
+	summary.TargetEntropy = countsEntropy(summary.TargetCounts)
+	return summary
+}
+
+// Determines, for each value of a feature, the class distribution of the instances having it. Values whose
+// distribution departs from the dataset's overall one are predictive on their own, before any tree is trained.
+// Weighted instances count for their weight, and instances without a value for the feature, or with FeatureMissing,
+// are left out.
+func (ds ClassifiedDataSet) TargetRateByValue(featureName string) map[Feature]map[Target]float64 {
+	valueCounts := make(map[Feature]float64)
+	rates := make(map[Feature]map[Target]float64)
+	for _, inst := range ds.Instances {
+		featureValue, ok := inst.FeatureValues[featureName]
+		if !ok || featureValue == FeatureMissing {
+			continue
+		}
+		if rates[featureValue] == nil {
+			rates[featureValue] = make(map[Target]float64)
+		}
+		rates[featureValue][inst.TargetValue] += inst.weight()
+		valueCounts[featureValue] += inst.weight()
+	}
+	for featureValue, targetCounts := range rates {
+		for target := range targetCounts {
+			targetCounts[target] /= valueCounts[featureValue]
+		}
+	}
+	return rates
+}
+
+// Partitions the dataset by the value of a feature, as a split on it would. The partitions share the dataset's
+// instances rather than copying them, and instances without the feature are left out.
+func (ds ClassifiedDataSet) SplitByFeature(featureName string) map[Feature]ClassifiedDataSet {
+	partitions := make(map[Feature]ClassifiedDataSet)
+	for _, inst := range ds.Instances {
+		if featureValue, ok := inst.FeatureValues[featureName]; ok {
+			partition := partitions[featureValue]
+			partition.Instances = append(partition.Instances, inst)
+			partitions[featureValue] = partition
+		}
+	}
+	return partitions
+}
+
+// Draws a bootstrap sample the size of the dataset, with replacement, where each instance's chance of being drawn
+// is proportional to the weight of its target. Upweighting a rare class oversamples it. Targets without a weight
+// count as 1, and negative weights as 0.
+func (ds ClassifiedDataSet) WeightedBootstrap(classWeights map[Target]float64, seed int64) ClassifiedDataSet {
+	sample, _ := weightedBootstrapSample(ds.Instances, classWeights, rand.New(rand.NewSource(seed)))
+	return ClassifiedDataSet{Instances: sample}
+}
+
+// Shuffles the dataset with the given seed and puts the first trainFraction of the instances in train and the rest
+// in test. Both share the dataset's instances rather than copying them.
+func (ds ClassifiedDataSet) Split(trainFraction float64, seed int64) (train, test ClassifiedDataSet) {
+	insts := shuffle(ds.Instances, rand.New(rand.NewSource(seed)))
+	trainSize := splitSize(len(insts), trainFraction)
+	return ClassifiedDataSet{insts[:trainSize]}, ClassifiedDataSet{insts[trainSize:]}
+}
+
+// Like Split, but splits each target's instances separately so train and test keep the dataset's target
+// distribution as closely as their sizes allow.
+func (ds ClassifiedDataSet) StratifiedSplit(trainFraction float64, seed int64) (train, test ClassifiedDataSet) {
+	byTarget := make(map[Target][]*Instance)
+	var targets []Target // In order of first appearance after shuffling, keeping the split deterministic for a seed
+	for _, inst := range shuffle(ds.Instances, rand.New(rand.NewSource(seed))) {
+		if _, ok := byTarget[inst.TargetValue]; !ok {
+			targets = append(targets, inst.TargetValue)
+		}
+		byTarget[inst.TargetValue] = append(byTarget[inst.TargetValue], inst)
+	}
+	for _, target := range targets {
+		insts := byTarget[target]
+		trainSize := splitSize(len(insts), trainFraction)
+		train.Instances = append(train.Instances, insts[:trainSize]...)
+		test.Instances = append(test.Instances, insts[trainSize:]...)
+	}
+	return train, test
+}
+
+// Groups features that carry largely the same information, i.e. whose pairwise mutual information in bits exceeds
+// threshold. A tree picks one of a redundant group arbitrarily, so the others only look unimportant. Groups are
+// linked transitively, only have two or more features, and are sorted by name.
+func (ds ClassifiedDataSet) RedundantFeatureGroups(threshold float64) [][]string {
+	var featureNames []string
+	for featureName := range featureSchema(ds) {
+		featureNames = append(featureNames, featureName)
+	}
+	sort.Strings(featureNames)
+
+	// Union-find over the feature indexes
+	parent := make([]int, len(featureNames))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i := range featureNames {
+		for j := i + 1; j < len(featureNames); j++ {
+			if ds.mutualInformation(featureNames[i], featureNames[j]) > threshold {
+				parent[find(j)] = find(i)
+			}
+		}
+	}
+
+	rootToGroup := make(map[int][]string)
+	var roots []int
+	for i, featureName := range featureNames {
+		root := find(i)
+		if _, ok := rootToGroup[root]; !ok {
+			roots = append(roots, root)
+		}
+		rootToGroup[root] = append(rootToGroup[root], featureName)
+	}
+	var groups [][]string
+	for _, root := range roots {
+		if len(rootToGroup[root]) > 1 {
+			groups = append(groups, rootToGroup[root])
+		}
+	}
+	return groups
+}
+
+// Calculates the mutual information between two features, H(A) + H(B) - H(A, B), in bits.
+func (ds ClassifiedDataSet) mutualInformation(featureA, featureB string) float64 {
+	aCounts, bCounts, jointCounts := make(map[Feature]int), make(map[Feature]int), make(map[[2]Feature]int)
+	for _, inst := range ds.Instances {
+		a, b := inst.FeatureValues[featureA], inst.FeatureValues[featureB]
+		aCounts[a]++
+		bCounts[b]++
+		jointCounts[[2]Feature{a, b}]++
+	}
+	H := func(count int) float64 {
+		pI := float64(count) / float64(len(ds.Instances))
+		return -pI * math.Log2(pI)
+	}
+	mutualInformation := 0.0
+	for _, count := range aCounts {
+		mutualInformation += H(count)
+	}
+	for _, count := range bCounts {
+		mutualInformation += H(count)
+	}
+	for _, count := range jointCounts {
+		mutualInformation -= H(count)
+	}
+	return mutualInformation
+}
+
+// Checks that every instance has the same features, since an absent feature would otherwise read as value 0. The
+// error names the first instance lacking any feature another instance has, and which ones it lacks.
+func checkFeatureSchema(ds ClassifiedDataSet) error {
+	schema := featureSchema(ds)
+	for i, inst := range ds.Instances {
+		if len(inst.FeatureValues) == len(schema) {
+			continue
+		}
+		var absent []string
+		for featureName := range schema {
+			if _, ok := inst.FeatureValues[featureName]; !ok {
+				absent = append(absent, featureName)
+			}
+		}
+		sort.Strings(absent)
+		return errors.New(fmt.Sprint("instance ", i, " is missing features ", absent))
+	}
+	return nil
+}
+
+// Collects the set of feature names used by any instance in the dataset.
+func featureSchema(ds ClassifiedDataSet) map[string]bool {
+	schema := make(map[string]bool)
+	for _, inst := range ds.Instances {
+		for featureName := range inst.FeatureValues {
+			schema[featureName] = true
+		}
+	}
+	return schema
+}
+
+// Creates a copy of the dataset whose instances only have the named features.
+func withFeatures(ds ClassifiedDataSet, featureNames []string) ClassifiedDataSet {
+	projected := ClassifiedDataSet{Instances: make([]*Instance, len(ds.Instances))}
+	for i, inst := range ds.Instances {
+		projected.Instances[i] = &Instance{FeatureValues: make(map[string]Feature, len(featureNames)), TargetValue: inst.TargetValue, Weight: inst.Weight}
+		for _, featureName := range featureNames {
+			if value, ok := inst.FeatureValues[featureName]; ok {
+				projected.Instances[i].FeatureValues[featureName] = value
+			}
+		}
+	}
+	return projected
+}
+
+// Returns a shuffled copy of the instances, leaving the original order untouched.
+func shuffle(insts []*Instance, rng *rand.Rand) []*Instance {
+	shuffled := make([]*Instance, len(insts))
+	for i, j := range rng.Perm(len(insts)) {
+		shuffled[i] = insts[j]
+	}
+	return shuffled
+}
+
+// The number of n instances that go to train, rounded to the nearest instance and clamped to [0, n].
+func splitSize(n int, trainFraction float64) int {
+	return int(math.Round(clamp(trainFraction, 0, 1) * float64(n)))
+}
@@ -0,0 +1,217 @@
+package id3
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConcat(t *testing.T) {
+	a := ClassifiedDataSet{
+		[]*Instance{
+			{map[string]Feature{"salty": 0, "sweet": 1}, true, 1},
+			{map[string]Feature{"salty": 1, "sweet": 0}, false, 1},
+		},
+	}
+	b := ClassifiedDataSet{
+		[]*Instance{
+			{map[string]Feature{"sweet": 1, "salty": 1}, true, 1},
+		},
+	}
+	c := ClassifiedDataSet{
+		[]*Instance{
+			{map[string]Feature{"salty": 1, "sour": 1}, false, 1},
+		},
+	}
+
+	if ds, err := a.Concat(b); err != nil {
+		t.Error("Encountered concat error", err)
+	} else if len(ds.Instances) != 3 || ds.Instances[0] != a.Instances[0] || ds.Instances[2] != b.Instances[0] {
+		t.Error("Expected instances of both datasets in order, got", ds.Instances)
+	}
+	if _, err := a.Concat(ClassifiedDataSet{}); err != nil {
+		t.Error("Expected an empty dataset to align, got", err)
+	}
+	if _, err := a.Concat(c); err == nil {
+		t.Error("Expected an error concatenating mismatched schemas")
+	}
+	if _, _, err := AlignDataSets(c, b); err == nil {
+		t.Error("Expected an error aligning mismatched schemas")
+	}
+
+	appended := ClassifiedDataSet{append([]*Instance{}, a.Instances...)}
+	if err := appended.Append(b); err != nil {
+		t.Error("Encountered append error", err)
+	} else if len(appended.Instances) != 3 || appended.Instances[2] != b.Instances[0] {
+		t.Error("Expected the instance of b appended, got", appended.Instances)
+	}
+	if err := appended.Append(c); err == nil || len(appended.Instances) != 3 {
+		t.Error("Expected an error appending mismatched schemas and no change, got", err, len(appended.Instances))
+	}
+	empty := ClassifiedDataSet{}
+	if err := empty.Append(c); err != nil || len(empty.Instances) != 1 {
+		t.Error("Expected to append to an empty dataset, got", err, empty.Instances)
+	}
+
+	if ds, err := ConcatDataSets(ClassifiedDataSet{}, a, b, a); err != nil {
+		t.Error("Encountered concat error", err)
+	} else if len(ds.Instances) != 5 || ds.Instances[4] != a.Instances[1] {
+		t.Error("Expected instances of every dataset in order, got", ds.Instances)
+	}
+	if _, err := ConcatDataSets(a, b, c); err == nil || !strings.HasPrefix(err.Error(), "dataset 2: ") {
+		t.Error("Expected an error naming dataset 2, got", err)
+	}
+	if ds, err := ConcatDataSets(); err != nil || len(ds.Instances) != 0 {
+		t.Error("Expected an empty dataset from nothing, got", ds, err)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	ds := tennisDataSet()
+	ds.Instances[0].FeatureValues["wind"] = FeatureMissing
+	summary := ds.Summary()
+	expectedValues := map[string]int{"outlook": 3, "temp": 3, "humidity": 2, "wind": 2}
+	if summary.Instances != 14 || !reflect.DeepEqual(summary.DistinctValues, expectedValues) {
+		t.Error("Expected 14 instances with", expectedValues, "got", summary.Instances, summary.DistinctValues)
+	}
+	if expectedCounts := map[Target]float64{true: 9, false: 5}; !reflect.DeepEqual(summary.TargetCounts, expectedCounts) {
+		t.Error("Expected", expectedCounts, "got", summary.TargetCounts)
+	}
+	if math.Abs(summary.TargetEntropy-0.940) > 1e-3 {
+		t.Error("Expected an entropy of about 0.940, got", summary.TargetEntropy)
+	}
+
+	if empty := (ClassifiedDataSet{}).Summary(); empty.Instances != 0 || len(empty.DistinctValues) != 0 || empty.TargetEntropy != 0 {
+		t.Error("Expected an empty summary, got", empty)
+	}
+}
+
+func TestTargetRateByValue(t *testing.T) {
+	expectedRates := map[Feature]map[Target]float64{
+		0: {true: 3.0 / 5, false: 2.0 / 5}, // Rain
+		1: {true: 1},                       // Overcast
+		2: {true: 2.0 / 5, false: 3.0 / 5}, // Sunny
+	}
+	if rates := tennisDataSet().TargetRateByValue("outlook"); !reflect.DeepEqual(rates, expectedRates) {
+		t.Error("Expected", expectedRates, "got", rates)
+	}
+
+	// Weights count, and instances without a value don't count towards any
+	ds := tennisDataSet()
+	ds.Instances[0].Weight = 3                                // Sunny, no
+	ds.Instances[3].FeatureValues["outlook"] = FeatureMissing // Rain, yes
+	delete(ds.Instances[4].FeatureValues, "outlook")          // Rain, yes
+	expectedRates = map[Feature]map[Target]float64{
+		0: {true: 1.0 / 3, false: 2.0 / 3},
+		1: {true: 1},
+		2: {true: 2.0 / 7, false: 5.0 / 7},
+	}
+	if rates := ds.TargetRateByValue("outlook"); !reflect.DeepEqual(rates, expectedRates) {
+		t.Error("Expected", expectedRates, "got", rates)
+	}
+}
+
+func TestRedundantFeatureGroups(t *testing.T) {
+	ds := tennisDataSet()
+	for _, inst := range ds.Instances {
+		inst.FeatureValues["outlook copy"] = inst.FeatureValues["outlook"]
+		inst.FeatureValues["windy"] = 1 - inst.FeatureValues["wind"]
+	}
+	expectedGroups := [][]string{{"outlook", "outlook copy"}, {"wind", "windy"}}
+	if groups := ds.RedundantFeatureGroups(0.9); !reflect.DeepEqual(groups, expectedGroups) {
+		t.Error("Expected", expectedGroups, "got", groups)
+	}
+	if groups := tennisDataSet().RedundantFeatureGroups(0.9); len(groups) != 0 {
+		t.Error("Expected no redundant features in the original data, got", groups)
+	}
+}
+
+func TestSplitByFeature(t *testing.T) {
+	ds := tennisDataSet()
+	partitions := ds.SplitByFeature("outlook")
+	expectedSizes := map[Feature]int{0: 5, 1: 4, 2: 5} // Rain, Overcast, Sunny
+	if len(partitions) != len(expectedSizes) {
+		t.Error("Expected 3 partitions, got", len(partitions))
+	}
+	for featureValue, size := range expectedSizes {
+		if len(partitions[featureValue].Instances) != size {
+			t.Error("Expected", size, "instances with outlook", featureValue, "got", len(partitions[featureValue].Instances))
+		}
+		for _, inst := range partitions[featureValue].Instances {
+			if inst.FeatureValues["outlook"] != featureValue {
+				t.Error("Expected outlook", featureValue, "got", inst.FeatureValues)
+			}
+		}
+	}
+	if partitions := ds.SplitByFeature("color"); len(partitions) != 0 {
+		t.Error("Expected no partitions for a missing feature, got", partitions)
+	}
+}
+
+func TestWeightedBootstrap(t *testing.T) {
+	ds := ClassifiedDataSet{}
+	for i := 0; i < 100; i++ {
+		ds.Instances = append(ds.Instances, &Instance{map[string]Feature{"signal": Feature(i % 10 / 9)}, Target(i%10 == 9), 1})
+	}
+	minorityFraction := func(insts []*Instance) float64 {
+		return targetDistribution(insts)[Target(true)] / float64(len(insts))
+	}
+
+	sample := ds.WeightedBootstrap(map[Target]float64{true: 9}, 1)
+	if len(sample.Instances) != len(ds.Instances) {
+		t.Error("Expected a sample of", len(ds.Instances), "instances, got", len(sample.Instances))
+	}
+	if fraction := minorityFraction(sample.Instances); fraction < 0.3 {
+		t.Error("Expected the minority class to be oversampled from 0.1 towards 0.5, got", fraction)
+	}
+
+	f, err := TrainForestWithOptions(ds, BestFeatureInformationGain, 5, 1, ForestOptions{ClassWeights: map[Target]float64{true: 9}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dtree := range f.Trees() {
+		if support := dtree.Stats().Distribution[Target(true)]; support < 30 {
+			t.Error("Expected each tree's sample to oversample the minority class, got", support, "of 100")
+		}
+	}
+	// The first tree's sample is the first weighted draw from the seed, with no uniform draw wasted before it
+	if _, oob := weightedBootstrapSample(ds.Instances, map[Target]float64{true: 9}, rand.New(rand.NewSource(1))); !reflect.DeepEqual(f.oob[0], oob) {
+		t.Error("Expected the first tree's out-of-bag instances to come from the first weighted draw")
+	}
+	if _, err := TrainForestWithOptions(ds, BestFeatureInformationGain, 5, 1, ForestOptions{ClassWeights: map[Target]float64{true: -1}}); err == nil {
+		t.Error("Expected an error with a negative class weight")
+	}
+}
+
+func TestSplit(t *testing.T) {
+	ds := tennisDataSet()
+	train, test := ds.Split(0.7, 1)
+	if len(train.Instances) != 10 || len(test.Instances) != 4 {
+		t.Error("Expected a 10/4 split, got", len(train.Instances), len(test.Instances))
+	}
+	seen := make(map[*Instance]bool)
+	for _, inst := range append(append([]*Instance{}, train.Instances...), test.Instances...) {
+		seen[inst] = true
+	}
+	if len(seen) != len(ds.Instances) {
+		t.Error("Expected every instance exactly once, got", len(seen), "distinct instances")
+	}
+	if again, _ := ds.Split(0.7, 1); !reflect.DeepEqual(again, train) {
+		t.Error("Expected the same split for the same seed")
+	}
+	if train, test := ds.Split(2, 1); len(train.Instances) != 14 || len(test.Instances) != 0 {
+		t.Error("Expected a fraction above 1 to put everything in train, got", len(train.Instances), len(test.Instances))
+	}
+
+	// 9 true and 5 false, so half of each rounds to 5 and 3
+	train, test = ds.StratifiedSplit(0.5, 1)
+	trainCounts, testCounts := targetDistribution(train.Instances), targetDistribution(test.Instances)
+	if trainCounts[Target(true)] != 5 || trainCounts[Target(false)] != 3 {
+		t.Error("Expected 5 true and 3 false in train, got", trainCounts)
+	}
+	if testCounts[Target(true)] != 4 || testCounts[Target(false)] != 2 {
+		t.Error("Expected 4 true and 2 false in test, got", testCounts)
+	}
+}
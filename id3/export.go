@@ -0,0 +1,181 @@
+package id3
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The most combinations ToLookupTable will enumerate.
+const maxLookupTableSize = 1 << 20
+
+// Converts the tree to nested plain data, e.g. for templating. Decision nodes become maps with a "feature" name and
+// "children" keyed by feature value (or "<=t" and ">t" for a threshold split), while output nodes have an "output" target and any "meta" from SetLeafMeta.
+// An optional decoder maps feature values back to their original labels for the children keys; values without a
+// label are written in decimal.
+func (dtree *Decision) ToMap(decoder ...map[string]map[Feature]string) map[string]interface{} {
+	var featureLabels map[string]map[Feature]string
+	if len(decoder) > 0 {
+		featureLabels = decoder[0]
+	}
+	return dtree.toMap(featureLabels)
+}
+
+func (dtree *Decision) toMap(featureLabels map[string]map[Feature]string) map[string]interface{} {
+	if dtree.isOutput {
+		out := map[string]interface{}{"output": dtree.outputValue}
+		if len(dtree.leafMeta) > 0 {
+			meta := make(map[string]interface{}, len(dtree.leafMeta))
+			for k, v := range dtree.leafMeta {
+				meta[k] = v
+			}
+			out["meta"] = meta
+		}
+		return out
+	}
+	children := make(map[string]interface{}, len(dtree.nextDecisions))
+	for featureValue, subtree := range dtree.nextDecisions {
+		key := featureLabel(featureLabels, dtree.featureName, featureValue)
+		if dtree.continuous && featureValue == 0 {
+			key = "<=" + strconv.Itoa(int(dtree.threshold))
+		} else if dtree.continuous {
+			key = ">" + strconv.Itoa(int(dtree.threshold))
+		}
+		children[key] = subtree.toMap(featureLabels)
+	}
+	return map[string]interface{}{"feature": dtree.featureName, "children": children}
+}
+
+// Looks up the label of a feature value, falling back to the value in decimal.
+func featureLabel(featureLabels map[string]map[Feature]string, featureName string, featureValue Feature) string {
+	if label, ok := featureLabels[featureName][featureValue]; ok {
+		return label
+	}
+	return strconv.Itoa(int(featureValue))
+}
+
+// Enumerates every combination of the features' domains and maps each, encoded with LookupKey, to the tree's
+// prediction, trading memory for traversal-free inference on low-dimensional data. Combinations the tree can't
+// classify, like ones with values it never saw, are left out. Errors if there are more combinations than a
+// safety cap of about a million.
+func (dtree *Decision) ToLookupTable(domains map[string]map[Feature]bool) (map[string]Target, error) {
+	featureNames := make([]string, 0, len(domains))
+	size := 1
+	for featureName, domain := range domains {
+		featureNames = append(featureNames, featureName)
+		if size *= len(domain); size > maxLookupTableSize {
+			return nil, errors.New(fmt.Sprint("more than ", maxLookupTableSize, " feature combinations"))
+		}
+	}
+	sort.Strings(featureNames)
+	values := make([][]Feature, len(featureNames))
+	for i, featureName := range featureNames {
+		for featureValue := range domains[featureName] {
+			values[i] = append(values[i], featureValue)
+		}
+	}
+
+	table := make(map[string]Target, size)
+	features := make(map[string]Feature, len(featureNames))
+	var enumerate func(i int)
+	enumerate = func(i int) {
+		if i == len(featureNames) {
+			if leaf, err := dtree.leafFor(features); err == nil {
+				table[LookupKey(features)] = leaf.outputValue
+			}
+			return
+		}
+		for _, featureValue := range values[i] {
+			features[featureNames[i]] = featureValue
+			enumerate(i + 1)
+		}
+	}
+	enumerate(0)
+	return table, nil
+}
+
+// Encodes feature values as a key of a table made by ToLookupTable, e.g. "salty=0,sweet=1". The features must be
+// exactly those of the table's domains.
+func LookupKey(features map[string]Feature) string {
+	parts := make([]string, 0, len(features))
+	for featureName, featureValue := range features {
+		parts = append(parts, featureName+"="+strconv.Itoa(int(featureValue)))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+// Writes the tree as a Graphviz digraph, e.g. for rendering with dot -Tpng. Decision nodes are labeled by their
+// feature and output nodes, drawn as boxes, by their target. Edges are labeled by feature value, or by "<=t" and
+// ">t" for a threshold split. Nodes are numbered in pre-order, following children in order of feature value.
+func (dtree *Decision) DOT(w io.Writer) error {
+	var buf bytes.Buffer
+	ids := make(map[*Decision]int)
+	var visit func(node *Decision) int
+	visit = func(node *Decision) int {
+		if id, ok := ids[node]; ok { // Merged feature values share a child
+			return id
+		}
+		id := len(ids)
+		ids[node] = id
+		if node.isOutput {
+			fmt.Fprintf(&buf, "\tn%d [label=%s, shape=box];\n", id, strconv.Quote(fmt.Sprint(node.outputValue)))
+			return id
+		}
+		fmt.Fprintf(&buf, "\tn%d [label=%s];\n", id, strconv.Quote(node.featureName))
+		for _, featureValue := range sortedFeatureValues(node.nextDecisions) {
+			childID := visit(node.nextDecisions[featureValue])
+			label := strconv.Itoa(int(featureValue))
+			if node.continuous && featureValue == 0 {
+				label = "<=" + strconv.Itoa(int(node.threshold))
+			} else if node.continuous {
+				label = ">" + strconv.Itoa(int(node.threshold))
+			}
+			fmt.Fprintf(&buf, "\tn%d -> n%d [label=%s];\n", id, childID, strconv.Quote(label))
+		}
+		return id
+	}
+	buf.WriteString("digraph {\n")
+	visit(dtree)
+	buf.WriteString("}\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Renders the tree as indented text for reading in a terminal, one node per line. Each decision node names its
+// feature, and its children follow, indented, each introduced by the feature value leading to it, or by "<= t" and
+// "> t" for a threshold split. Output nodes show their target and, when trained, the weight n of the training
+// instances that reached them and the purity, the share of that weight with the target, as in "true (n=42,
+// purity=0.952)". Fractional weights, as from missing values, are rounded to three decimals.
+func (dtree *Decision) Pretty() string {
+	var sb strings.Builder
+	var visit func(node *Decision, indent string)
+	visit = func(node *Decision, indent string) {
+		if stats := node.Stats(); node.isOutput && stats.Support > 0 {
+			purity := stats.Distribution[node.outputValue] / stats.Support
+			fmt.Fprintf(&sb, "%v (n=%s, purity=%.3g)\n", node.outputValue, strconv.FormatFloat(math.Round(stats.Support*1000)/1000, 'f', -1, 64), purity)
+			return
+		} else if node.isOutput {
+			fmt.Fprintln(&sb, node.outputValue)
+			return
+		}
+		fmt.Fprintln(&sb, node.featureName)
+		for _, featureValue := range sortedFeatureValues(node.nextDecisions) {
+			branch := "= " + strconv.Itoa(int(featureValue))
+			if node.continuous && featureValue == 0 {
+				branch = "<= " + strconv.Itoa(int(node.threshold))
+			} else if node.continuous {
+				branch = "> " + strconv.Itoa(int(node.threshold))
+			}
+			sb.WriteString(indent + "  " + branch + ": ")
+			visit(node.nextDecisions[featureValue], indent+"    ")
+		}
+	}
+	visit(dtree, "")
+	return sb.String()
+}
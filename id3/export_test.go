@@ -0,0 +1,158 @@
+package id3
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Counts the decision levels in a map produced by ToMap.
+func mapDepth(m map[string]interface{}) int {
+	children, ok := m["children"].(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	depth := 0
+	for _, child := range children {
+		if childDepth := mapDepth(child.(map[string]interface{})); childDepth > depth {
+			depth = childDepth
+		}
+	}
+	return depth + 1
+}
+
+func TestToMap(t *testing.T) {
+	dtree, err := Train(tennisDataSet(), BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal("Encountered tree training error", err)
+	}
+
+	m := dtree.ToMap()
+	if depth := mapDepth(m); depth != 2 {
+		t.Error("Expected a map of depth 2, got", depth, m)
+	}
+	if m["feature"] != "outlook" {
+		t.Error("Expected outlook at the root, got", m["feature"])
+	}
+
+	labels := map[string]map[Feature]string{"outlook": {0: "rain", 1: "overcast", 2: "sunny"}}
+	children := dtree.ToMap(labels)["children"].(map[string]interface{})
+	if overcast, ok := children["overcast"].(map[string]interface{}); !ok || overcast["output"] != Target(true) {
+		t.Error("Expected overcast to be labeled and output true, got", children)
+	}
+	if rain, ok := children["rain"].(map[string]interface{}); !ok || rain["children"].(map[string]interface{})["1"] == nil {
+		t.Error("Expected unlabeled wind values in decimal under rain, got", children)
+	}
+}
+
+func TestToLookupTable(t *testing.T) {
+	candy := ClassifiedDataSet{
+		[]*Instance{
+			{map[string]Feature{"salty": 0, "sweet": 0}, false, 1},
+			{map[string]Feature{"salty": 1, "sweet": 0}, false, 1},
+			{map[string]Feature{"salty": 1, "sweet": 1}, true, 1},
+			{map[string]Feature{"salty": 0, "sweet": 1}, true, 1},
+		},
+	}
+	dtree, err := Train(candy, BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	domain := map[Feature]bool{0: true, 1: true}
+	table, err := dtree.ToLookupTable(map[string]map[Feature]bool{"salty": domain, "sweet": domain})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(table) != 4 {
+		t.Error("Expected 4 entries, got", table)
+	}
+	for _, inst := range candy.Instances {
+		classified := inst.Clone()
+		if err := dtree.Classify(classified); err != nil {
+			t.Error(err)
+		} else if target, ok := table[LookupKey(inst.FeatureValues)]; !ok || target != classified.TargetValue {
+			t.Error("Expected", classified.TargetValue, "for", LookupKey(inst.FeatureValues), "got", target)
+		}
+	}
+
+	wide := make(map[Feature]bool, 256)
+	for v := 0; v < 256; v++ {
+		wide[Feature(v)] = true
+	}
+	if _, err := dtree.ToLookupTable(map[string]map[Feature]bool{"salty": wide, "sweet": wide, "sour": wide}); err == nil {
+		t.Error("Expected an error past the size cap")
+	}
+}
+
+func TestDOT(t *testing.T) {
+	dtree := &Decision{featureName: "sweet", nextDecisions: map[Feature]*Decision{
+		0: {isOutput: true, outputValue: false},
+		1: {featureName: "salty", continuous: true, threshold: 2, nextDecisions: map[Feature]*Decision{
+			0: {isOutput: true, outputValue: true},
+			1: {isOutput: true, outputValue: false},
+		}},
+	}}
+	var buf bytes.Buffer
+	if err := dtree.DOT(&buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := `digraph {
+	n0 [label="sweet"];
+	n1 [label="false", shape=box];
+	n0 -> n1 [label="0"];
+	n2 [label="salty"];
+	n3 [label="true", shape=box];
+	n2 -> n3 [label="<=2"];
+	n4 [label="false", shape=box];
+	n2 -> n4 [label=">2"];
+	n0 -> n2 [label="1"];
+}
+`
+	if buf.String() != expected {
+		t.Error("Expected", expected, "got", buf.String())
+	}
+}
+
+func TestPretty(t *testing.T) {
+	dtree, err := Train(tennisDataSet(), BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `outlook
+  = 0: wind
+      = 0: true (n=3, purity=1)
+      = 1: false (n=2, purity=1)
+  = 1: true (n=4, purity=1)
+  = 2: humidity
+      = 0: true (n=2, purity=1)
+      = 1: false (n=3, purity=1)
+`
+	if pretty := dtree.Pretty(); pretty != expected {
+		t.Error("Expected", expected, "got", pretty)
+	}
+	stump, err := TrainWithOptions(tennisDataSet(), Options{MaxDepth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = `outlook
+  = 0: true (n=5, purity=0.6)
+  = 1: true (n=4, purity=1)
+  = 2: false (n=5, purity=0.6)
+`
+	if pretty := stump.Pretty(); pretty != expected {
+		t.Error("Expected", expected, "got", pretty)
+	}
+	leaf := &Decision{isOutput: true, outputValue: true, distribution: map[Target]float64{true: 3 + 3.0/13, false: 1}}
+	if pretty := leaf.Pretty(); pretty != "true (n=4.231, purity=0.764)\n" {
+		t.Error("Expected fractional weights rounded, got", pretty)
+	}
+
+	thresholded := &Decision{featureName: "age", continuous: true, threshold: 61, nextDecisions: map[Feature]*Decision{
+		0: {isOutput: true, outputValue: "young"},
+		1: {isOutput: true, outputValue: "old"},
+	}}
+	if pretty := thresholded.Pretty(); pretty != "age\n  <= 61: young\n  > 61: old\n" {
+		t.Error("Expected threshold branches, got", pretty)
+	}
+	if pretty := (&Decision{isOutput: true, outputValue: true}).Pretty(); pretty != "true\n" {
+		t.Error("Expected a lone leaf, got", pretty)
+	}
+}
@@ -0,0 +1,534 @@
+package id3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// An ensemble of decision trees, each trained on a bootstrap sample of the same dataset, that classifies by vote.
+// Every tree remembers the instances left out of its sample (its out-of-bag instances) for error estimates.
+type Forest struct {
+	trees       []*Decision
+	oob         [][]*Instance
+	calibration *plattCalibration
+	opts        ForestOptions
+}
+
+// Knobs for TrainForestWithOptions. The zero value trains a plain bagged forest.
+type ForestOptions struct {
+	// Relative sampling weight of each target in the bootstrap samples, such as to oversample a rare class.
+	// Targets without a weight count as 1, and a nil map samples every instance uniformly.
+	ClassWeights map[Target]float64
+	// Number of features drawn at random at each split for the BestFeatureFunc to choose among, decorrelating the
+	// trees. Zero lets every split consider every feature.
+	FeaturesPerSplit int
+}
+
+// A bagged ensemble of trees, as built by Bagging. Each tree sees every feature, unlike in a RandomForest.
+type BaggedModel = Forest
+
+// Trains numModels trees on bootstrap resamples of the dataset that classify by majority vote, i.e. TrainForest.
+// The same seed always produces the same model.
+func Bagging(ds ClassifiedDataSet, bf BestFeatureFunc, numModels int, seed int64) (*BaggedModel, error) {
+	return TrainForest(ds, bf, numModels, seed)
+}
+
+// A forest whose splits each consider a random subset of the features, as built by NewRandomForest.
+type RandomForest = Forest
+
+// Trains a random forest of numTrees information gain trees, each on its own bootstrap sample and with every split
+// choosing among featuresPerSplit randomly drawn features. The same seed always produces the same forest.
+func NewRandomForest(ds ClassifiedDataSet, numTrees, featuresPerSplit int, seed int64) (*RandomForest, error) {
+	if featuresPerSplit < 1 {
+		return nil, errors.New(fmt.Sprint("need at least one feature per split, got ", featuresPerSplit))
+	}
+	return TrainForestWithOptions(ds, BestFeatureInformationGain, numTrees, seed, ForestOptions{FeaturesPerSplit: featuresPerSplit})
+}
+
+// Trains a forest of numTrees trees, each on its own bootstrap sample of the dataset. The samples are drawn from
+// a RNG seeded with seed, so the same inputs always produce the same forest.
+func TrainForest(ds ClassifiedDataSet, bf BestFeatureFunc, numTrees int, seed int64) (*Forest, error) {
+	return TrainForestWithOptions(ds, bf, numTrees, seed, ForestOptions{})
+}
+
+// Trains a forest like TrainForest, with the sampling set in opts. Trees added later by Grow use opts as well.
+func TrainForestWithOptions(ds ClassifiedDataSet, bf BestFeatureFunc, numTrees int, seed int64, opts ForestOptions) (*Forest, error) {
+	f, _, err := TrainForestContext(context.Background(), ds, bf, numTrees, seed, opts)
+	return f, err
+}
+
+// Trains a forest like TrainForestWithOptions, but stops adding trees once ctx is done, such as when its deadline
+// passes. The trees built so far still make a usable forest, and are the same as the first trees of an
+// uninterrupted run. At least one tree is always built. Also returns how many trees were built.
+func TrainForestContext(ctx context.Context, ds ClassifiedDataSet, bf BestFeatureFunc, numTrees int, seed int64, opts ForestOptions) (*Forest, int, error) {
+	if len(ds.Instances) == 0 {
+		return nil, 0, errors.New("no instances provided")
+	} else if numTrees < 1 {
+		return nil, 0, errors.New(fmt.Sprint("need at least one tree, got ", numTrees))
+	}
+	for target, weight := range opts.ClassWeights {
+		if weight < 0 {
+			return nil, 0, errors.New(fmt.Sprint("negative class weight ", weight, " for target ", target))
+		}
+	}
+	if opts.FeaturesPerSplit < 0 {
+		return nil, 0, errors.New(fmt.Sprint("cannot consider ", opts.FeaturesPerSplit, " features per split"))
+	}
+	f := &Forest{trees: make([]*Decision, 0, numTrees), oob: make([][]*Instance, 0, numTrees), opts: opts}
+	if err := f.grow(ctx, ds, bf, numTrees, seed); err != nil {
+		return nil, 0, err
+	}
+	return f, len(f.trees), nil
+}
+
+// Warm-starts an existing forest, appending additionalTrees trees trained on fresh bootstrap samples of ds. The
+// existing trees and their out-of-bag instances are kept as they are. A calibrated forest should be recalibrated
+// afterwards, since the vote fractions it was fit on have shifted.
+func (f *Forest) Grow(ds ClassifiedDataSet, bf BestFeatureFunc, additionalTrees int, seed int64) error {
+	if len(ds.Instances) == 0 {
+		return errors.New("no instances provided")
+	} else if additionalTrees < 0 {
+		return errors.New(fmt.Sprint("cannot grow by ", additionalTrees, " trees"))
+	}
+	return f.grow(context.Background(), ds, bf, additionalTrees, seed)
+}
+
+// Appends up to additionalTrees trees, stopping early once ctx is done as long as the forest has a tree.
+func (f *Forest) grow(ctx context.Context, ds ClassifiedDataSet, bf BestFeatureFunc, additionalTrees int, seed int64) error {
+	rng := rand.New(rand.NewSource(seed))
+	for i := 0; i < additionalTrees; i++ {
+		if len(f.trees) > 0 && ctx.Err() != nil {
+			break
+		}
+		var sample, oob []*Instance
+		if f.opts.ClassWeights != nil {
+			sample, oob = weightedBootstrapSample(ds.Instances, f.opts.ClassWeights, rng)
+		} else {
+			sample, oob = bootstrapSample(ds.Instances, rng)
+		}
+		treeBF := bf
+		if f.opts.FeaturesPerSplit > 0 {
+			treeBF = randomSubspace(bf, f.opts.FeaturesPerSplit, rng.Int63())
+		}
+		dtree, err := Train(ClassifiedDataSet{Instances: sample}, treeBF)
+		if err != nil {
+			return err
+		}
+		f.trees, f.oob = append(f.trees, dtree), append(f.oob, oob)
+	}
+	return nil
+}
+
+// Creates a deep copy of the forest, so that its trees can be pruned or it can be calibrated without affecting
+// the original. The out-of-bag instances are training data, so they're shared rather than copied.
+func (f *Forest) Clone() *Forest {
+	clone := &Forest{trees: make([]*Decision, len(f.trees)), oob: make([][]*Instance, len(f.oob)), opts: f.opts}
+	for i, dtree := range f.trees {
+		clone.trees[i] = dtree.Clone()
+	}
+	for i, oob := range f.oob {
+		clone.oob[i] = append([]*Instance{}, oob...)
+	}
+	if f.calibration != nil {
+		calibration := *f.calibration
+		clone.calibration = &calibration
+	}
+	return clone
+}
+
+// The trees making up the forest.
+func (f *Forest) Trees() []*Decision {
+	return f.trees
+}
+
+// Determines the fraction of the forest's trees voting for each target. Trees that can't classify the instance,
+// such as from never having seen one of its values, abstain. Once the forest is calibrated, the probabilities are
+// calibrated ones instead.
+func (f *Forest) ClassifyProbabilities(inst *Instance) (map[Target]float64, error) {
+	votes, err := f.votes(inst, f.trees)
+	if err != nil {
+		return nil, err
+	}
+	if f.calibration != nil {
+		positive := f.calibration.probability(votes[Target(true)])
+		return map[Target]float64{true: positive, false: 1 - positive}, nil
+	}
+	return votes, nil
+}
+
+// Attempt to classify a provided instance of data by majority vote of the trees. The classification is set in the
+// instance's TargetValue field.
+func (f *Forest) Classify(inst *Instance) error {
+	target, err := f.Predict(inst)
+	if err != nil {
+		return err
+	}
+	inst.TargetValue = target
+	return nil
+}
+
+// Finds the target the majority of the trees vote for, leaving the instance untouched.
+func (f *Forest) Predict(inst *Instance) (Target, error) {
+	votes, err := f.votes(inst, f.trees)
+	if err != nil {
+		return nil, err
+	}
+	return mostVotedTarget(votes), nil
+}
+
+// Estimates the forest's generalization error without a held out dataset. Each training instance is classified
+// by the vote of only those trees whose bootstrap sample left it out.
+func (f *Forest) OOBError() (float64, error) {
+	instToTrees := make(map[*Instance][]*Decision)
+	var insts []*Instance // Keeps evaluation order deterministic
+	for i, oob := range f.oob {
+		for _, inst := range oob {
+			if _, ok := instToTrees[inst]; !ok {
+				insts = append(insts, inst)
+			}
+			instToTrees[inst] = append(instToTrees[inst], f.trees[i])
+		}
+	}
+
+	wrongClassifications, classified := 0.0, 0
+	for _, inst := range insts {
+		votes, err := f.votes(inst, instToTrees[inst])
+		if err != nil { // None of the trees could classify it
+			continue
+		}
+		classified++
+		if mostVotedTarget(votes) != inst.TargetValue {
+			wrongClassifications++
+		}
+	}
+	if classified == 0 {
+		return 0, errors.New("no out-of-bag instances could be classified")
+	}
+	return wrongClassifications / float64(classified), nil
+}
+
+// Measures each feature's importance as the mean decrease in accuracy, across trees, from shuffling that feature's
+// values among the tree's out-of-bag instances. Shuffling a feature the trees rely on breaks its link with the
+// target and costs accuracy, while shuffling an irrelevant one costs nothing. The instances aren't modified.
+func (f *Forest) OOBImportance(seed int64) (map[string]float64, error) {
+	var featureNames []string // Sorted, so the shuffles are deterministic for a given seed
+	for featureName := range featureSchema(ClassifiedDataSet{Instances: f.oobInstances()}) {
+		featureNames = append(featureNames, featureName)
+	}
+	sort.Strings(featureNames)
+
+	rng := rand.New(rand.NewSource(seed))
+	importances := make(map[string]float64, len(featureNames))
+	trees := 0
+	for i, oob := range f.oob {
+		if len(oob) == 0 {
+			continue
+		}
+		trees++
+		baseWrong := f.trees[i].misclassified(oob)
+		for _, featureName := range featureNames {
+			permuted := make([]*Instance, len(oob))
+			for j, k := range rng.Perm(len(oob)) {
+				permuted[j] = oob[j].Clone()
+				if value, ok := oob[k].FeatureValues[featureName]; ok {
+					permuted[j].FeatureValues[featureName] = value
+				} else {
+					delete(permuted[j].FeatureValues, featureName)
+				}
+			}
+			importances[featureName] += (f.trees[i].misclassified(permuted) - baseWrong) / totalWeight(oob)
+		}
+	}
+	if trees == 0 {
+		return nil, errors.New("no tree has out-of-bag instances")
+	}
+	for featureName := range importances {
+		importances[featureName] /= float64(trees)
+	}
+	return importances, nil
+}
+
+// Lists every instance left out of at least one tree's bootstrap sample.
+func (f *Forest) oobInstances() []*Instance {
+	seen := make(map[*Instance]bool)
+	var insts []*Instance
+	for _, oob := range f.oob {
+		for _, inst := range oob {
+			if !seen[inst] {
+				seen[inst] = true
+				insts = append(insts, inst)
+			}
+		}
+	}
+	return insts
+}
+
+// Fits a Platt scaling of the forest's positive (true) vote fraction on a validation set, correcting systematic
+// over or under-confidence of the raw votes. ClassifyProbabilities reports calibrated probabilities afterwards.
+func (f *Forest) Calibrate(validate ClassifiedDataSet) error {
+	if len(validate.Instances) == 0 {
+		return errors.New("no instances provided")
+	}
+	scores, positives := make([]float64, 0, len(validate.Instances)), make([]bool, 0, len(validate.Instances))
+	for _, inst := range validate.Instances {
+		votes, err := f.votes(inst, f.trees)
+		if err != nil {
+			return err
+		}
+		scores, positives = append(scores, votes[Target(true)]), append(positives, inst.TargetValue == Target(true))
+	}
+	f.calibration = fitPlattCalibration(scores, positives)
+	return nil
+}
+
+// Scores how structurally alike two forests are, from 0 to 1. Trees are compared by the Jaccard similarity of
+// their sets of root-to-leaf paths; each tree is matched with its most similar tree in the other forest, and the
+// matches are averaged in both directions. A forest compared with itself scores 1.
+func ForestSimilarity(a, b *Forest) float64 {
+	if len(a.trees) == 0 || len(b.trees) == 0 {
+		return 0
+	}
+	aPaths, bPaths := forestPathSets(a), forestPathSets(b)
+	bestMatches := func(from, to []map[string]bool) float64 {
+		total := 0.0
+		for _, fromPaths := range from {
+			best := 0.0
+			for _, toPaths := range to {
+				if similarity := jaccard(fromPaths, toPaths); similarity > best {
+					best = similarity
+				}
+			}
+			total += best
+		}
+		return total / float64(len(from))
+	}
+	return (bestMatches(aPaths, bPaths) + bestMatches(bPaths, aPaths)) / 2
+}
+
+// Collects each tree's paths as a set of String() lines.
+func forestPathSets(f *Forest) []map[string]bool {
+	pathSets := make([]map[string]bool, len(f.trees))
+	for i, dtree := range f.trees {
+		pathSets[i] = make(map[string]bool)
+		for _, path := range dtree.String() {
+			pathSets[i][path] = true
+		}
+	}
+	return pathSets
+}
+
+// Size of the intersection of two sets over the size of their union.
+func jaccard(a, b map[string]bool) float64 {
+	intersection := 0
+	for k := range a {
+		if b[k] {
+			intersection++
+		}
+	}
+	union := len(a) + len(b) - intersection
+	if union == 0 {
+		return 1
+	}
+	return float64(intersection) / float64(union)
+}
+
+// Tallies the fraction of the provided trees voting for each target. Each tree votes for what its Predict gives,
+// so instances missing values are voted on within every tree too.
+func (f *Forest) votes(inst *Instance, trees []*Decision) (map[Target]float64, error) {
+	votes := make(map[Target]float64)
+	total := 0
+	for _, dtree := range trees {
+		target, err := dtree.predict(inst.FeatureValues)
+		if err != nil {
+			continue
+		}
+		votes[target]++
+		total++
+	}
+	if total == 0 {
+		return nil, errors.New(fmt.Sprint("no tree could classify feature values ", inst.FeatureValues))
+	}
+	for target := range votes {
+		votes[target] /= float64(total)
+	}
+	return votes, nil
+}
+
+// Picks the target with the largest share of the vote, ties going to the smaller target.
+func mostVotedTarget(votes map[Target]float64) Target {
+	var highestTarget Target
+	highestVote := -1.0
+	for target, vote := range votes {
+		if vote > highestVote || vote == highestVote && targetLess(target, highestTarget) {
+			highestVote, highestTarget = vote, target
+		}
+	}
+	return highestTarget
+}
+
+// Wraps bf so that each split only considers featuresPerSplit features drawn at random. The draw is seeded by seed
+// and the instances reaching the node, so it doesn't depend on the order the nodes are trained in.
+func randomSubspace(bf BestFeatureFunc, featuresPerSplit int, seed int64) BestFeatureFunc {
+	if bf == nil {
+		bf = BestFeatureInformationGain
+	}
+	return func(ds ClassifiedDataSet) string {
+		var featureNames []string
+		for featureName := range featureSchema(ds) {
+			featureNames = append(featureNames, featureName)
+		}
+		if len(featureNames) <= featuresPerSplit {
+			return bf(ds)
+		}
+		sort.Strings(featureNames)
+
+		h := fnv.New64a()
+		for _, inst := range ds.Instances {
+			for _, featureName := range featureNames {
+				value := inst.FeatureValues[featureName]
+				h.Write([]byte{byte(value), byte(value >> 8)})
+			}
+		}
+		rng := rand.New(rand.NewSource(seed ^ int64(h.Sum64())))
+		chosen := make([]string, featuresPerSplit)
+		for i, j := range rng.Perm(len(featureNames))[:featuresPerSplit] {
+			chosen[i] = featureNames[j]
+		}
+		return bf(withFeatures(ds, chosen))
+	}
+}
+
+// Draws len(insts) instances with replacement, also returning the instances that were never drawn.
+func bootstrapSample(insts []*Instance, rng *rand.Rand) (sample, oob []*Instance) {
+	drawn := make([]bool, len(insts))
+	sample = make([]*Instance, len(insts))
+	for i := range sample {
+		j := rng.Intn(len(insts))
+		sample[i], drawn[j] = insts[j], true
+	}
+	for i, inst := range insts {
+		if !drawn[i] {
+			oob = append(oob, inst)
+		}
+	}
+	return sample, oob
+}
+
+// Draws len(insts) instances with replacement like bootstrapSample, but with each instance's chance of being
+// drawn proportional to the weight of its target. Falls back to uniform sampling when every weight is 0.
+func weightedBootstrapSample(insts []*Instance, classWeights map[Target]float64, rng *rand.Rand) (sample, oob []*Instance) {
+	cumulative := make([]float64, len(insts))
+	total := 0.0
+	for i, inst := range insts {
+		weight, ok := classWeights[inst.TargetValue]
+		if !ok {
+			weight = 1
+		}
+		total += math.Max(weight, 0)
+		cumulative[i] = total
+	}
+	if total == 0 {
+		return bootstrapSample(insts, rng)
+	}
+
+	drawn := make([]bool, len(insts))
+	sample = make([]*Instance, len(insts))
+	for i := range sample {
+		x := rng.Float64() * total
+		j := sort.Search(len(cumulative), func(j int) bool { return cumulative[j] > x })
+		sample[i], drawn[j] = insts[j], true
+	}
+	for i, inst := range insts {
+		if !drawn[i] {
+			oob = append(oob, inst)
+		}
+	}
+	return sample, oob
+}
+
+// A sigmoid mapping of a raw score to a probability, P(positive) = 1 / (1 + exp(A*score + B)).
+type plattCalibration struct {
+	A, B float64
+}
+
+func (c *plattCalibration) probability(score float64) float64 {
+	return 1 / (1 + math.Exp(c.A*score+c.B))
+}
+
+// Fits a Platt sigmoid by Newton's method with backtracking, following Lin, Lin and Weng's numerically stable
+// formulation. Targets are smoothed towards 1/2 by the class counts to avoid overfitting small validation sets.
+func fitPlattCalibration(scores []float64, positives []bool) *plattCalibration {
+	prior1, prior0 := 0.0, 0.0
+	for _, positive := range positives {
+		if positive {
+			prior1++
+		} else {
+			prior0++
+		}
+	}
+	hiTarget, loTarget := (prior1+1)/(prior1+2), 1/(prior0+2)
+	targets := make([]float64, len(scores))
+	for i, positive := range positives {
+		if positive {
+			targets[i] = hiTarget
+		} else {
+			targets[i] = loTarget
+		}
+	}
+
+	// Negative log likelihood of the targets under a sigmoid, computed without overflowing exp
+	objective := func(a, b float64) float64 {
+		fval := 0.0
+		for i, score := range scores {
+			if fApB := score*a + b; fApB >= 0 {
+				fval += targets[i]*fApB + math.Log1p(math.Exp(-fApB))
+			} else {
+				fval += (targets[i]-1)*fApB + math.Log1p(math.Exp(fApB))
+			}
+		}
+		return fval
+	}
+
+	c := &plattCalibration{A: 0, B: math.Log((prior0 + 1) / (prior1 + 1))}
+	fval := objective(c.A, c.B)
+	for iter := 0; iter < 100; iter++ {
+		// Gradient and Hessian, with the Hessian's diagonal nudged to stay positive definite
+		h11, h22, h21, g1, g2 := 1e-12, 1e-12, 0.0, 0.0, 0.0
+		for i, score := range scores {
+			var p, q float64
+			if fApB := score*c.A + c.B; fApB >= 0 {
+				p, q = math.Exp(-fApB)/(1+math.Exp(-fApB)), 1/(1+math.Exp(-fApB))
+			} else {
+				p, q = 1/(1+math.Exp(fApB)), math.Exp(fApB)/(1+math.Exp(fApB))
+			}
+			d2 := p * q
+			h11, h22, h21 = h11+score*score*d2, h22+d2, h21+score*d2
+			d1 := targets[i] - p
+			g1, g2 = g1+score*d1, g2+d1
+		}
+		if math.Abs(g1) < 1e-5 && math.Abs(g2) < 1e-5 { // Converged
+			break
+		}
+
+		det := h11*h22 - h21*h21
+		dA, dB := -(h22*g1-h21*g2)/det, -(-h21*g1+h11*g2)/det
+		gd := g1*dA + g2*dB
+		stepSize := 1.0
+		for ; stepSize >= 1e-10; stepSize /= 2 { // Backtrack until the objective decreases enough
+			newA, newB := c.A+stepSize*dA, c.B+stepSize*dB
+			if newF := objective(newA, newB); newF < fval+1e-4*stepSize*gd {
+				c.A, c.B, fval = newA, newB, newF
+				break
+			}
+		}
+		if stepSize < 1e-10 { // Line search failed
+			break
+		}
+	}
+	return c
+}
@@ -0,0 +1,309 @@
+package id3
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Mean negative log likelihood of the actual targets, with probabilities clipped away from 0 and 1.
+func forestLogLoss(t *testing.T, f *Forest, ds ClassifiedDataSet) float64 {
+	loss := 0.0
+	for _, inst := range ds.Instances {
+		probs, err := f.ClassifyProbabilities(inst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		loss -= math.Log(math.Min(math.Max(probs[inst.TargetValue], 1e-15), 1-1e-15))
+	}
+	return loss / float64(len(ds.Instances))
+}
+
+// A dataset where "signal" decides the target, but a flipRate fraction of the labels are flipped at random.
+func flippedDataSet(n int, flipRate float64, seed int64) ClassifiedDataSet {
+	rng := rand.New(rand.NewSource(seed))
+	ds := ClassifiedDataSet{}
+	for i := 0; i < n; i++ {
+		signal := Feature(rng.Intn(2))
+		ds.Instances = append(ds.Instances, &Instance{
+			map[string]Feature{"signal": signal, "other": Feature(rng.Intn(2))},
+			Target(signal == 1) != Target(rng.Float64() < flipRate),
+			1,
+		})
+	}
+	return ds
+}
+
+func TestTrainForest(t *testing.T) {
+	ds := tennisDataSet()
+	f, err := TrainForest(ds, BestFeatureInformationGain, 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(f.Trees()) != 10 || len(f.oob) != 10 {
+		t.Fatal("Expected 10 trees with out-of-bag instances, got", len(f.Trees()), len(f.oob))
+	}
+
+	for _, inst := range ds.Instances {
+		probs, err := f.ClassifyProbabilities(inst)
+		if err != nil {
+			t.Error(err)
+		} else if math.Abs(probs[Target(true)]+probs[Target(false)]-1) > 1e-9 {
+			t.Error("Expected vote fractions to sum to 1, got", probs)
+		}
+	}
+	if oobError, err := f.OOBError(); err != nil {
+		t.Error(err)
+	} else if oobError < 0 || oobError > 1 {
+		t.Error("Expected an OOB error between 0 and 1, got", oobError)
+	}
+
+	if _, err := TrainForest(ClassifiedDataSet{}, BestFeatureInformationGain, 10, 1); err == nil {
+		t.Error("Expected an error training on no instances")
+	}
+}
+
+func TestForestMissingValues(t *testing.T) {
+	ds := tennisDataSet()
+	f, err := TrainForest(ds, BestFeatureInformationGain, 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every tree reads outlook on the path this instance takes
+	inst := &Instance{map[string]Feature{"outlook": FeatureMissing, "temp": 1, "humidity": 1, "wind": 0}, nil, 1}
+	expectedVotes := make(map[Target]float64)
+	for _, dtree := range f.Trees() {
+		target, err := dtree.Predict(inst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedVotes[target] += 1.0 / 10
+	}
+	if probs, err := f.ClassifyProbabilities(inst); err != nil {
+		t.Error(err)
+	} else {
+		for target, vote := range expectedVotes {
+			if math.Abs(probs[target]-vote) > 1e-9 {
+				t.Error("Expected every tree to vote as it predicts,", expectedVotes, "got", probs)
+			}
+		}
+	}
+	if target, err := f.Predict(inst); err != nil || target != mostVotedTarget(expectedVotes) {
+		t.Error("Expected", mostVotedTarget(expectedVotes), "got", target, err)
+	}
+
+	// Out-of-bag instances missing values are voted on as well
+	for _, inst := range ds.Instances {
+		inst.FeatureValues["outlook"] = FeatureMissing
+	}
+	if oobError, err := f.OOBError(); err != nil || oobError < 0 || oobError > 1 {
+		t.Error("Expected an OOB error between 0 and 1, got", oobError, err)
+	}
+}
+
+func TestBagging(t *testing.T) {
+	ds := flippedDataSet(100, 0.1, 1)
+	bagged, err := Bagging(ds, BestFeatureInformationGain, 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := TrainForest(ds, BestFeatureInformationGain, 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(bagged, f) {
+		t.Error("Expected the same trees as a forest with the same seed")
+	}
+	for _, dtree := range bagged.Trees() {
+		if dtree.featureName != "signal" {
+			t.Error("Expected every tree to see and split on signal, got", dtree.featureName)
+		}
+	}
+	if _, err := Bagging(ds, BestFeatureInformationGain, 0, 1); err == nil {
+		t.Error("Expected an error with no models")
+	}
+}
+
+func TestNewRandomForest(t *testing.T) {
+	ds := tennisDataSet()
+	f, err := NewRandomForest(ds, 20, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := NewRandomForest(ds, 20, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(again, f) {
+		t.Error("Expected the same forest for the same seed")
+	}
+
+	// With one feature per split, the roots can't all be the most informative feature
+	roots := make(map[string]bool)
+	for _, dtree := range f.Trees() {
+		roots[dtree.featureName] = true
+	}
+	if len(roots) < 2 {
+		t.Error("Expected roots split on several features, got", roots)
+	}
+
+	for _, inst := range ds.Instances {
+		if target, err := f.Predict(inst); err != nil {
+			t.Error(err)
+		} else if classified := inst.Clone(); f.Classify(classified) != nil || classified.TargetValue != target {
+			t.Error("Expected Classify to agree with Predict's", target, "got", classified.TargetValue)
+		}
+	}
+
+	if _, err := NewRandomForest(ds, 20, 0, 1); err == nil {
+		t.Error("Expected an error with no features per split")
+	}
+}
+
+func TestTrainForestContext(t *testing.T) {
+	ds := flippedDataSet(100, 0.1, 1)
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now())
+	defer cancel()
+	f, built, err := TrainForestContext(ctx, ds, BestFeatureInformationGain, 1000, 1, ForestOptions{})
+	if err != nil {
+		t.Fatal(err)
+	} else if built >= 1000 || built != len(f.Trees()) || built < 1 {
+		t.Fatal("Expected a partial forest of at least one tree, got", built, "reported and", len(f.Trees()), "trees")
+	}
+
+	full, err := TrainForest(ds, BestFeatureInformationGain, built, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, dtree := range f.Trees() {
+		if !reflect.DeepEqual(dtree, full.Trees()[i]) {
+			t.Error("Expected tree", i, "to match an uninterrupted run")
+		}
+	}
+	for _, inst := range ds.Instances {
+		if err := f.Classify(inst.Clone()); err != nil {
+			t.Error("Expected the partial forest to classify, got", err)
+		}
+	}
+}
+
+func TestGrow(t *testing.T) {
+	ds := flippedDataSet(100, 0.1, 1)
+	f, err := TrainForest(ds, BestFeatureInformationGain, 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := append([]*Decision{}, f.Trees()...)
+
+	if err := f.Grow(ds, BestFeatureInformationGain, 5, 2); err != nil {
+		t.Fatal(err)
+	} else if len(f.Trees()) != 15 || len(f.oob) != 15 {
+		t.Fatal("Expected 15 trees with out-of-bag instances, got", len(f.Trees()), len(f.oob))
+	}
+	for i, dtree := range original {
+		if f.Trees()[i] != dtree {
+			t.Error("Expected existing tree", i, "to be kept")
+		}
+	}
+	if _, err := f.OOBError(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestForestClone(t *testing.T) {
+	ds := flippedDataSet(200, 0.2, 1)
+	train, validate := ClassifiedDataSet{ds.Instances[:100]}, ClassifiedDataSet{ds.Instances[100:]}
+	f, err := TrainForest(train, BestFeatureInformationGain, 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	predictions := func(f *Forest) []map[Target]float64 {
+		var probs []map[Target]float64
+		for _, inst := range validate.Instances {
+			instProbs, err := f.ClassifyProbabilities(inst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			probs = append(probs, instProbs)
+		}
+		return probs
+	}
+	before := predictions(f)
+
+	clone := f.Clone()
+	for _, dtree := range clone.Trees() {
+		if _, err := dtree.ReducedErrorPrune(validate); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := clone.Calibrate(validate); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(predictions(f), before) {
+		t.Error("Expected pruning and calibrating the clone to leave the original's predictions unchanged")
+	}
+	if f.calibration != nil || len(clone.Trees()) != len(f.Trees()) || clone.Trees()[0] == f.Trees()[0] {
+		t.Error("Expected the clone to have its own copies of the trees")
+	}
+}
+
+func TestOOBImportance(t *testing.T) {
+	f, err := TrainForest(flippedDataSet(200, 0.1, 1), BestFeatureInformationGain, 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	importances, err := f.OOBImportance(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if importances["signal"] <= importances["other"] || importances["signal"] < 0.2 {
+		t.Error("Expected signal to be far more important than other, got", importances)
+	}
+	if _, err := (&Forest{}).OOBImportance(1); err == nil {
+		t.Error("Expected an error without out-of-bag instances")
+	}
+}
+
+func TestCalibrate(t *testing.T) {
+	// Trees vote almost unanimously for the majority label, but it's wrong a fifth of the time
+	ds := flippedDataSet(300, 0.2, 1)
+	train := ClassifiedDataSet{ds.Instances[:100]}
+	validate := ClassifiedDataSet{ds.Instances[100:200]}
+	test := ClassifiedDataSet{ds.Instances[200:]}
+
+	f, err := TrainForest(train, BestFeatureInformationGain, 15, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawLoss := forestLogLoss(t, f, test)
+	if err := f.Calibrate(validate); err != nil {
+		t.Fatal(err)
+	}
+	calibratedLoss := forestLogLoss(t, f, test)
+	if calibratedLoss >= rawLoss {
+		t.Error("Expected calibration to lower log-loss, got", rawLoss, "then", calibratedLoss)
+	}
+}
+
+func TestForestSimilarity(t *testing.T) {
+	f, err := TrainForest(randomBinaryDataSet(200, 6, 1), BestFeatureInformationGain, 8, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if similarity := ForestSimilarity(f, f); similarity != 1 {
+		t.Error("Expected a forest to be fully similar to itself, got", similarity)
+	}
+
+	other, err := TrainForest(randomBinaryDataSet(200, 6, 2), BestFeatureInformationGain, 8, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if similarity := ForestSimilarity(f, other); similarity <= 0 || similarity >= 1 {
+		t.Error("Expected forests from different samples to be partly similar, got", similarity)
+	} else if ForestSimilarity(other, f) != similarity {
+		t.Error("Expected similarity to be symmetric")
+	}
+	if similarity := ForestSimilarity(f, &Forest{}); similarity != 0 {
+		t.Error("Expected an empty forest to have no similarity, got", similarity)
+	}
+}
@@ -0,0 +1,185 @@
+package id3
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+)
+
+// The gob form of a tree node. Nodes are flattened into a slice in pre-order, and children refer to their index
+// in it, so the whole tree is a single gob value rather than one nested encoding per node.
+type gobNode struct {
+	FeatureName  string
+	Continuous   bool
+	Threshold    Feature
+	IsOutput     bool
+	OutputValue  Target
+	Gain         float64
+	Distribution map[Target]float64
+	Children     map[Feature]int
+	LeafMeta     map[string]interface{}
+}
+
+// Encodes the whole tree for encoding/gob, so a *Decision can be passed straight to a gob.Encoder. Targets and
+// leaf metadata of types other than Go's basic ones must be registered with gob.Register first.
+func (dtree *Decision) GobEncode() ([]byte, error) {
+	var nodes []gobNode
+	indexes := make(map[*Decision]int)
+	var flatten func(node *Decision) int
+	flatten = func(node *Decision) int {
+		if i, ok := indexes[node]; ok { // Merged feature values share a child
+			return i
+		}
+		i := len(nodes)
+		indexes[node] = i
+		nodes = append(nodes, gobNode{
+			FeatureName: node.featureName, Continuous: node.continuous, Threshold: node.threshold,
+			IsOutput: node.isOutput, OutputValue: node.outputValue, Gain: node.gain,
+			Distribution: node.distribution, LeafMeta: node.leafMeta,
+		})
+		if node.nextDecisions != nil {
+			children := make(map[Feature]int, len(node.nextDecisions))
+			for featureValue, subtree := range node.nextDecisions {
+				children[featureValue] = flatten(subtree)
+			}
+			nodes[i].Children = children
+		}
+		return i
+	}
+	flatten(dtree)
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(nodes); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decodes a tree encoded by GobEncode.
+func (dtree *Decision) GobDecode(data []byte) error {
+	var nodes []gobNode
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&nodes); err != nil {
+		return err
+	} else if len(nodes) == 0 {
+		return errors.New("no tree nodes encoded")
+	}
+	decisions := make([]*Decision, len(nodes))
+	decisions[0] = dtree
+	for i := 1; i < len(nodes); i++ {
+		decisions[i] = &Decision{}
+	}
+	for i, node := range nodes {
+		*decisions[i] = Decision{
+			featureName: node.FeatureName, continuous: node.Continuous, threshold: node.Threshold,
+			isOutput: node.IsOutput, outputValue: node.OutputValue, gain: node.Gain,
+			distribution: node.Distribution, leafMeta: node.LeafMeta,
+		}
+		if node.Children != nil {
+			decisions[i].nextDecisions = make(map[Feature]*Decision, len(node.Children))
+			for featureValue, j := range node.Children {
+				if j <= i || j >= len(nodes) { // Children always follow their parent in pre-order
+					return errors.New(fmt.Sprint("invalid child index ", j, " for node ", i))
+				}
+				decisions[i].nextDecisions[featureValue] = decisions[j]
+			}
+		}
+	}
+	return nil
+}
+
+// The gob form of a forest. Out-of-bag instances are often left out of several trees' samples, so each is encoded
+// once and the trees refer to them by index.
+type gobForest struct {
+	Trees            []*Decision
+	Instances        []*Instance
+	OOB              [][]int
+	Calibration      *plattCalibration
+	ClassWeights     map[Target]float64
+	FeaturesPerSplit int
+}
+
+// Encodes the whole forest for encoding/gob, including each tree's out-of-bag instances, so OOB estimates and Grow
+// still work after reloading, and any calibration. Targets of types other than Go's basic ones must be registered
+// with gob.Register first.
+func (f *Forest) GobEncode() ([]byte, error) {
+	encoded := gobForest{
+		Trees:        f.trees,
+		OOB:          make([][]int, len(f.oob)),
+		Calibration:  f.calibration,
+		ClassWeights: f.opts.ClassWeights, FeaturesPerSplit: f.opts.FeaturesPerSplit,
+	}
+	indexes := make(map[*Instance]int)
+	for i, oob := range f.oob {
+		encoded.OOB[i] = make([]int, len(oob))
+		for j, inst := range oob {
+			index, ok := indexes[inst]
+			if !ok {
+				index = len(encoded.Instances)
+				indexes[inst] = index
+				encoded.Instances = append(encoded.Instances, inst)
+			}
+			encoded.OOB[i][j] = index
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(encoded); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decodes a forest encoded by GobEncode.
+func (f *Forest) GobDecode(data []byte) error {
+	var encoded gobForest
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&encoded); err != nil {
+		return err
+	} else if len(encoded.OOB) != len(encoded.Trees) {
+		return errors.New(fmt.Sprint("out-of-bag instances for ", len(encoded.OOB), " trees, but ", len(encoded.Trees), " trees"))
+	}
+	*f = Forest{
+		trees:       encoded.Trees,
+		oob:         make([][]*Instance, len(encoded.OOB)),
+		calibration: encoded.Calibration,
+		opts:        ForestOptions{ClassWeights: encoded.ClassWeights, FeaturesPerSplit: encoded.FeaturesPerSplit},
+	}
+	for i, indexes := range encoded.OOB {
+		f.oob[i] = make([]*Instance, len(indexes))
+		for j, index := range indexes {
+			if index < 0 || index >= len(encoded.Instances) {
+				return errors.New(fmt.Sprint("invalid instance index ", index, " for tree ", i))
+			}
+			f.oob[i][j] = encoded.Instances[index]
+		}
+	}
+	return nil
+}
+
+// The gob form of a boosted model: its stumps, and the weight of each one's vote.
+type gobBoostedModel struct {
+	Stumps []*Decision
+	Alphas []float64
+}
+
+// Encodes the boosted model for encoding/gob. Targets of types other than Go's basic ones must be registered with
+// gob.Register first.
+func (m *BoostedModel) GobEncode() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(gobBoostedModel{m.stumps, m.alphas}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decodes a boosted model encoded by GobEncode.
+func (m *BoostedModel) GobDecode(data []byte) error {
+	var encoded gobBoostedModel
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&encoded); err != nil {
+		return err
+	} else if len(encoded.Stumps) != len(encoded.Alphas) {
+		return errors.New(fmt.Sprint(len(encoded.Stumps), " stumps, but ", len(encoded.Alphas), " votes"))
+	}
+	*m = BoostedModel{stumps: encoded.Stumps, alphas: encoded.Alphas}
+	return nil
+}
@@ -0,0 +1,108 @@
+package id3
+
+import (
+	"bytes"
+	"encoding/gob"
+	"reflect"
+	"testing"
+)
+
+func TestDecisionGob(t *testing.T) {
+	ds := tennisDataSet()
+	dtree, err := Train(ds, BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dtree.SetLeafMeta("action", func(leaf *Decision) interface{} { return "play" })
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(dtree); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := &Decision{}
+	if err := gob.NewDecoder(&buf).Decode(reloaded); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(reloaded, dtree) {
+		t.Error("Expected", dtree.String(), "got", reloaded.String())
+	}
+	for _, inst := range ds.Instances {
+		if target, err := reloaded.Predict(inst); err != nil {
+			t.Error(err)
+		} else if target != inst.TargetValue {
+			t.Error("Expected", inst.TargetValue, "got", target, "for", inst.FeatureValues)
+		}
+	}
+
+	merged, err := TrainWithOptions(agesDataSet(), Options{MaxChildren: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := gob.NewEncoder(&buf).Encode(merged); err != nil {
+		t.Fatal(err)
+	}
+	reloaded = &Decision{}
+	if err := gob.NewDecoder(&buf).Decode(reloaded); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(reloaded, merged) || reloaded.nextDecisions[98] == nil || reloaded.nextDecisions[98] != reloaded.nextDecisions[96] {
+		t.Error("Expected merged values to keep sharing their child")
+	}
+}
+
+func TestForestGob(t *testing.T) {
+	ds := tennisDataSet()
+	f, err := TrainForestWithOptions(ds, BestFeatureInformationGain, 7, 1, ForestOptions{FeaturesPerSplit: 2})
+	if err != nil {
+		t.Fatal(err)
+	} else if err := f.Calibrate(ds); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(f); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := &RandomForest{}
+	if err := gob.NewDecoder(&buf).Decode(reloaded); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(reloaded, f) {
+		t.Error("Expected the reloaded forest to equal the original")
+	}
+	for _, inst := range ds.Instances {
+		expected, _ := f.ClassifyProbabilities(inst)
+		if probs, err := reloaded.ClassifyProbabilities(inst); err != nil || !reflect.DeepEqual(probs, expected) {
+			t.Error("Expected", expected, "got", probs, err)
+		}
+	}
+	expectedOOBError, _ := f.OOBError()
+	if oobError, err := reloaded.OOBError(); err != nil || oobError != expectedOOBError {
+		t.Error("Expected an out-of-bag error of", expectedOOBError, "got", oobError, err)
+	}
+	// Instances left out of several trees stay shared between them
+	if expected, distinct := len(f.oobInstances()), len(reloaded.oobInstances()); distinct != expected {
+		t.Error("Expected", expected, "distinct out-of-bag instances, got", distinct)
+	}
+}
+
+func TestBoostedModelGob(t *testing.T) {
+	ds := majorityDataSet()
+	m, err := AdaBoost(ds, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(m); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := &BoostedModel{}
+	if err := gob.NewDecoder(&buf).Decode(reloaded); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(reloaded, m) {
+		t.Error("Expected the reloaded model to equal the original")
+	}
+	for _, inst := range ds.Instances {
+		if target, err := reloaded.Predict(inst); err != nil || target != inst.TargetValue {
+			t.Error("Expected", inst.TargetValue, "got", target, err)
+		}
+	}
+}
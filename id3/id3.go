@@ -4,7 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"reflect"
+	"runtime"
 	"sort"
+	"sync"
 )
 
 // Decision tree node type.
@@ -14,61 +17,118 @@ import (
 type Decision struct {
 	nextDecisions map[Feature]*Decision
 	featureName   string
+	continuous    bool    // Whether the split is a threshold one, sending values at most threshold to child 0 and
+	threshold     Feature // greater ones to child 1
 	isOutput      bool
 	outputValue   Target
+	gain          float64            // Information gain of the split made here
+	distribution  map[Target]float64 // Target counts of the training instances that reached this node
+	leafMeta      map[string]interface{}
 }
 
 // Convert a decision tree to a sorted string slice of all possible paths to output nodes.
 // Useful for debugging or equality-check purposes.
 func (dtree *Decision) String() []string {
-	paths := dtree.string(nil)
-	sort.Strings(paths)
-	return paths
+	return dtree.StringLabeled(nil, nil)
 }
 
-// Recursively determines a Decision tree's 'pathways'.
-func (dtree *Decision) string(parents []*Decision) []string {
-	if dtree.isOutput { // Output nodes actually return a slice of one element, the path to reach them.
+// Like String, but writes feature values and targets by their labels where one is given, e.g. from
+// Vocabulary.Labels. Values without a label are written as String would.
+func (dtree *Decision) StringLabeled(featureLabels map[string]map[Feature]string, targetLabels map[Target]string) []string {
+	var paths []string
+	for _, path := range dtree.Paths() {
 		sout := ""
-		for i, parent := range parents { // Iterate over parents, building the path
-			var featureVal Feature
-			var pChild *Decision
-			if i+1 < len(parents) {
-				pChild = parents[i+1]
-			} else {
-				pChild = dtree
-			}
-			for k, v := range parent.nextDecisions {
-				if v == pChild {
-					featureVal = k
-					break
-				}
+		for _, step := range path.Steps { // Build the path
+			condition := step.condition()
+			if step.Comparison == EqualTo {
+				condition = featureLabel(featureLabels, step.FeatureName, step.Value)
 			}
-			sout += fmt.Sprintf("%v[%v] ==> ", parent.featureName, featureVal)
+			sout += fmt.Sprintf("%v[%v] ==> ", step.FeatureName, condition)
 		}
 		// Add the output node value at the end
-		sout += fmt.Sprintf("%#v", dtree.outputValue)
-		return []string{sout}
-	} else { // Non-output nodes are added to the parents slice that is passed in further
-		var sout []string
-		if parents == nil {
-			parents = make([]*Decision, 0)
+		if label, ok := targetLabels[path.Output]; ok {
+			sout += label
+		} else {
+			sout += fmt.Sprintf("%#v", path.Output)
 		}
-		parents = append(parents, dtree)
-		for _, subtree := range dtree.nextDecisions { // Append every subtree's output to this output
-			sout = append(sout, subtree.string(parents)...)
+		paths = append(paths, sout)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Creates a deep copy of the whole tree, e.g. to prune the copy while keeping the original.
+func (dtree *Decision) Clone() *Decision {
+	return dtree.clone(make(map[*Decision]*Decision))
+}
+
+// Checks whether two trees make the same decisions: the same splits, with the same children for the same feature
+// values, down to the same outputs. Training statistics such as gains and distributions aren't compared. A nil
+// tree only equals another nil tree.
+func (dtree *Decision) Equal(other *Decision) bool {
+	if dtree == nil || other == nil {
+		return dtree == other
+	}
+	if dtree.featureName != other.featureName || dtree.isOutput != other.isOutput || dtree.outputValue != other.outputValue ||
+		dtree.continuous != other.continuous || dtree.threshold != other.threshold ||
+		len(dtree.nextDecisions) != len(other.nextDecisions) {
+		return false
+	}
+	for featureValue, subtree := range dtree.nextDecisions {
+		if !subtree.Equal(other.nextDecisions[featureValue]) {
+			return false
 		}
-		return sout
 	}
+	return true
 }
 
-// The type used for decision tree features. Up to 256 discrete values are allowed.
+// Deep copies the tree, reusing the copies in cloned so children shared by several feature values stay shared.
+func (dtree *Decision) clone(cloned map[*Decision]*Decision) *Decision {
+	if clone, ok := cloned[dtree]; ok {
+		return clone
+	}
+	clone := &Decision{}
+	*clone = *dtree
+	cloned[dtree] = clone
+	if dtree.nextDecisions != nil {
+		clone.nextDecisions = make(map[Feature]*Decision, len(dtree.nextDecisions))
+		for k, v := range dtree.nextDecisions {
+			clone.nextDecisions[k] = v.clone(cloned)
+		}
+	}
+	if dtree.distribution != nil {
+		clone.distribution = make(map[Target]float64, len(dtree.distribution))
+		for k, v := range dtree.distribution {
+			clone.distribution[k] = v
+		}
+	}
+	if dtree.leafMeta != nil {
+		clone.leafMeta = make(map[string]interface{}, len(dtree.leafMeta))
+		for k, v := range dtree.leafMeta {
+			clone.leafMeta[k] = v
+		}
+	}
+	return clone
+}
+
+// The type used for decision tree features. Up to 65536 discrete values are allowed, enough for high-cardinality
+// categorical columns such as IDs or zip codes, at two bytes per value. That is twice the memory of a single byte,
+// but a small part of an instance next to its map overhead. Loaders error rather than wrap past the limit.
 // The trainer builds the tree assuming that the only possible feature values are those specified
 // in the provided dataset
-type Feature uint8
+type Feature uint16
 
-// The type used for decision tree targets, or outputs.
-type Target bool
+// Marks a feature value as unknown, as with a "?" in the data. Training spreads the instances missing the value of
+// a split's feature across every branch, weighted in proportion to the training weight of each branch, as C4.5
+// does. Likewise, classifying one follows every branch and weighs the votes of the leaves it reaches. Loaders never
+// assign it to a label.
+const FeatureMissing = ^Feature(0)
+
+// The type used for decision tree targets, or outputs. Any comparable value can be a target, so besides the
+// original true and false, multi-class problems can use e.g. strings or integers for their classes. Targets are
+// compared and used as map keys, so one dataset's targets should all share a concrete type: 1 and uint8(1) are
+// different classes. Code that converted a Target to bool should use a type assertion, t.(bool), instead.
+type Target interface{}
 
 // A set of pointers to classified data.
 type ClassifiedDataSet struct {
@@ -80,11 +140,14 @@ type ClassifiedDataSet struct {
 type Instance struct {
 	FeatureValues map[string]Feature
 	TargetValue   Target
+	// How much the instance counts for in training and in CalculateError, such as to upweight a rare class
+	// instead of duplicating its rows. Zero counts as 1, so unweighted instances needn't set it.
+	Weight float64
 }
 
 // Creates a duplicate or deep clone of an instance.
 func (i *Instance) Clone() *Instance {
-	clone := &Instance{}
+	clone := &Instance{Weight: i.Weight}
 	clone.TargetValue, clone.FeatureValues = i.TargetValue, make(map[string]Feature, len(i.FeatureValues))
 	for k, v := range i.FeatureValues {
 		clone.FeatureValues[k] = v
@@ -92,71 +155,267 @@ func (i *Instance) Clone() *Instance {
 	return clone
 }
 
+// The instance's weight, with zero standing in for the default of 1.
+func (i *Instance) weight() float64 {
+	if i.Weight == 0 {
+		return 1
+	}
+	return i.Weight
+}
+
 // A type of function that selects the best feature for the decision tree to build upon.
 // One BestFeatureFunc using information gain is provided.
+// Training may call it from several goroutines at once, so it must be safe for concurrent use.
 type BestFeatureFunc func(ds ClassifiedDataSet) string
 
+// Knobs for TrainWithOptions. The zero value of each field leaves that knob disabled.
+type Options struct {
+	// Selects the feature to split on at each node. BestFeatureInformationGain is used when nil.
+	BestFeature BestFeatureFunc
+	// A split is rejected in favor of a leaf unless it removes at least this fraction of the node's entropy.
+	// Because it is normalized by the node's own entropy, the same threshold means the same thing whichever
+	// BestFeatureFunc chose the feature.
+	MinImpurityDecreaseFraction float64
+	// A split is rejected in favor of a leaf unless its information gain is at least this many bits. Unlike
+	// MinImpurityDecreaseFraction, the threshold is absolute, so it prunes more of the splits far from the root.
+	MinGain float64
+	// A split is rejected in favor of a leaf unless a chi-squared test finds its branches associated with the
+	// target at this significance level, i.e. with a p-value below it. 0.05 is a common choice. Unlike the gain
+	// thresholds, the test accounts for how many instances reach the node, so it stops splits on small samples.
+	ChiSquareAlpha float64
+	// Nodes this many splits below the root become leaves. Zero leaves depth unbounded.
+	MaxDepth int
+	// Nodes with fewer training instances than this become leaves predicting their majority target, however
+	// weighted the instances are.
+	MinSamplesSplit int
+	// Splits on a feature with more values than this merge the least populated values into a single shared
+	// child, so every merged value routes to the same subtree. Values below 2 leave splits unbounded.
+	MaxChildren int
+	// Features to treat as ordered numbers rather than categories. Splits on them are binary, at the threshold
+	// with the greatest information gain, and a feature can be split on again further down at another
+	// threshold. Values never seen in training are routed by comparison, so they classify too. A threshold split
+	// is made when its information gain beats that of BestFeature's pick among the other features.
+	ContinuousFeatures map[string]bool
+	// Declares, for binary targets, that the predicted probability of true never decreases (+1) or never increases
+	// (-1) as a feature's value grows, treating its values as ordered. Constrained features are never merged by
+	// MaxChildren.
+	MonotonicConstraints map[string]int
+	// Lets instances lack features that others have, reading the absent values as 0, rather than failing training.
+	// FeatureMissing is usually the better way to leave values out.
+	AllowInconsistentFeatures bool
+}
+
 // Using a classified set of data and the provided BestFeatureFunc, the ID3 algorithm is run to train and return
 // a decision tree.
 func Train(ds ClassifiedDataSet, bf BestFeatureFunc) (*Decision, error) {
-	// Infinitely bounded trainng
-	return LimitedTrain(ds, bf, int((^uint(0)) >> 1))
+	return TrainWithOptions(ds, Options{BestFeature: bf})
+}
+
+// Trains like Train, but with no path from the root to a leaf taking more than maxDepth splits, so a maxDepth of 1
+// gives a stump and one of 0 or less a lone leaf predicting the majority target.
+func LimitedTrain(ds ClassifiedDataSet, bf BestFeatureFunc, maxDepth int) (*Decision, error) {
+	if maxDepth > 0 {
+		return TrainWithOptions(ds, Options{BestFeature: bf, MaxDepth: maxDepth})
+	} else if err := checkFeatureSchema(ds); err != nil {
+		return nil, err
+	} else if len(ds.Instances) == 0 {
+		return nil, errors.New("no instances provided")
+	}
+	return &Decision{distribution: targetDistribution(ds.Instances), isOutput: true, outputValue: mostPopularTarget(ds.Instances)}, nil
 }
 
-// Allows for training with a specified maximum number of iterations
-func LimitedTrain(ds ClassifiedDataSet, bf BestFeatureFunc, iterations int) (*Decision, error) {
-	return limitedTrain(ds, bf, &iterations)
+// Trains a decision tree like Train, with the extra stopping criteria set in opts.
+func TrainWithOptions(ds ClassifiedDataSet, opts Options) (*Decision, error) {
+	if !opts.AllowInconsistentFeatures {
+		if err := checkFeatureSchema(ds); err != nil {
+			return nil, err
+		}
+	}
+	var dtree *Decision
+	if b := newBinaryDataSet(ds, opts.BestFeature); b != nil && len(opts.ContinuousFeatures) == 0 && opts.ChiSquareAlpha == 0 { // All-binary features can take the bitset path
+		dtree = b.train(fullMask(len(ds.Instances)), make([]bool, len(b.featureNames)), opts, 0)
+	} else if tree, err := limitedTrain(ds, opts, 0, true); err != nil {
+		return nil, err
+	} else {
+		dtree = tree
+	}
+	if len(opts.MonotonicConstraints) > 0 {
+		dtree.enforceMonotonic(opts.MonotonicConstraints, 0, 1)
+	}
+	return dtree, nil
 }
 
-func limitedTrain(ds ClassifiedDataSet, bf BestFeatureFunc, iterations *int) (*Decision, error) {
-	dtree := &Decision{} // The decision tree node to return
+// Bounds the goroutines training subtrees in parallel, across every tree being trained.
+var trainWorkers = make(chan struct{}, runtime.GOMAXPROCS(0))
+
+// Subtrees with fewer instances than this train faster than a goroutine starts, so aren't worth handing off.
+const parallelTrainMinInstances = 64
+
+// Trains the subtree for a node depth splits below the root. With parallel set, subtrees with enough instances are
+// handed to other goroutines while workers are free; the tree is the same either way.
+func limitedTrain(ds ClassifiedDataSet, opts Options, depth int, parallel bool) (*Decision, error) {
+	bf := opts.BestFeature
+	if bf == nil {
+		bf = BestFeatureInformationGain
+	}
+	dtree := &Decision{distribution: targetDistribution(ds.Instances)} // The decision tree node to return
 	if ds.Instances == nil || len(ds.Instances) == 0 { // Can't train with no data
 		return nil, errors.New("no instances provided")
-	} else if *iterations <= 0 { // Iteration bound has been reached
+	} else if opts.MaxDepth > 0 && depth >= opts.MaxDepth || // Depth bound has been reached
+		len(ds.Instances) < opts.MinSamplesSplit { // Too few instances to split
 		dtree.outputValue, dtree.isOutput, dtree.featureName = mostPopularTarget(ds.Instances), true, ""
 		return dtree, nil
-	} else if dtree.featureName = bf(ds); dtree.featureName == "" { // No features left
+	} else if featuresIdentical(ds.Instances) { // No split can separate the instances, even if their targets differ
+		dtree.outputValue, dtree.isOutput = mostPopularTarget(ds.Instances), true
+		return dtree, nil
+	} else if dtree.featureName, dtree.continuous, dtree.threshold = chooseSplit(ds, bf, opts.ContinuousFeatures); dtree.featureName == "" { // No features left
 		dtree.outputValue, dtree.isOutput = mostPopularTarget(ds.Instances), true
 		return dtree, nil
+	} else if !hasFeature(ds.Instances, dtree.featureName) { // Splitting on it would put every instance in one bucket
+		return nil, errors.New(fmt.Sprint("best feature function picked ", dtree.featureName, ", which no instance has"))
 	} else if instancesIdentical(ds.Instances) { // All instances are the same
-		dtree.outputValue, dtree.isOutput = ds.Instances[0].TargetValue, true
+		dtree.outputValue, dtree.isOutput, dtree.continuous, dtree.threshold = ds.Instances[0].TargetValue, true, false, 0
+		return dtree, nil
+	} else if len(knownInstances(ds.Instances, dtree.featureName)) == 0 { // Every instance is missing the feature
+		dtree.outputValue, dtree.isOutput, dtree.featureName = mostPopularTarget(ds.Instances), true, ""
+		dtree.continuous, dtree.threshold = false, 0
+		return dtree, nil
+	} else if dtree.gain = dtree.splitGain(ds); opts.MinImpurityDecreaseFraction > 0 &&
+		dtree.gain/Entropy(ds.Instances) < opts.MinImpurityDecreaseFraction ||
+		opts.MinGain > 0 && dtree.gain < opts.MinGain ||
+		opts.ChiSquareAlpha > 0 && dtree.chiSquarePValue(ds.Instances) >= opts.ChiSquareAlpha { // Split isn't worth it
+		dtree.outputValue, dtree.isOutput, dtree.featureName, dtree.gain = mostPopularTarget(ds.Instances), true, "", 0
+		dtree.continuous, dtree.threshold = false, 0
 		return dtree, nil
 	} else { // Make a decision node that will have children
-		*iterations -= 1 // This node
-		// Sort instances into buckets by feature value
+		// Sort instances into buckets by feature value, or by side of the threshold. The buckets hold clones with
+		// the feature removed, so the caller's instances are never written to and can be shared by concurrent
+		// training. Continuous features stay, to be split again at other thresholds. Instances missing the value
+		// are set aside to join every bucket.
 		bestFeatureValToInstances := make(map[Feature][]*Instance, len(ds.Instances))
+		var missing []*Instance
 		for _, inst := range ds.Instances {
-			instances, ok := bestFeatureValToInstances[inst.FeatureValues[dtree.featureName]]
+			if inst.FeatureValues[dtree.featureName] == FeatureMissing {
+				missing = append(missing, inst)
+				continue
+			}
+			key := dtree.branchKey(inst.FeatureValues[dtree.featureName])
+			instances, ok := bestFeatureValToInstances[key]
 			if !ok {
 				instances = make([]*Instance, 0)
 			}
-			bestFeatureValToInstances[inst.FeatureValues[dtree.featureName]] = append(instances, inst)
+			clone := inst.Clone()
+			if !dtree.continuous {
+				delete(clone.FeatureValues, dtree.featureName)
+			}
+			bestFeatureValToInstances[key] = append(instances, clone)
 		}
 
-		// Clone dataset so features can be removed
-		ds = ClassifiedDataSet{append([]*Instance{}, ds.Instances...)}
-		for i := range ds.Instances {
-			ds.Instances[i] = ds.Instances[i].Clone()
-			delete(ds.Instances[i].FeatureValues, dtree.featureName)
+		// Create subdecisions, one per group of feature values
+		maxChildren := opts.MaxChildren
+		if opts.MonotonicConstraints[dtree.featureName] != 0 { // Merged values would have no order
+			maxChildren = 0
 		}
-
-		// Create subdecisions
+		groups := groupFeatureValues(bestFeatureValToInstances, maxChildren)
 		dtree.nextDecisions = make(map[Feature]*Decision, len(bestFeatureValToInstances))
-		*iterations -= len(bestFeatureValToInstances) // Anticipated nodes
-		for k, v := range bestFeatureValToInstances {
-			var err error
-			dtree.nextDecisions[k], err = limitedTrain(ClassifiedDataSet{Instances: v}, bf, iterations)
-			if err != nil {
-				return nil, errors.New(fmt.Sprint("no instances available to extend tree for feature", dtree.featureName, "with value", k, "this shouldn't be possible"))
+		subtrees, errs := make([]*Decision, len(groups)), make([]error, len(groups))
+		knownWeight := totalWeight(ds.Instances) - totalWeight(missing)
+		var wg sync.WaitGroup
+		for i, group := range groups {
+			var insts []*Instance
+			for _, k := range group {
+				insts = append(insts, bestFeatureValToInstances[k]...)
+			}
+			fraction := totalWeight(insts) / knownWeight
+			for _, inst := range missing { // Weighted by the share of the known instances taking this branch
+				clone := inst.Clone()
+				clone.Weight = inst.weight() * fraction
+				if !dtree.continuous {
+					delete(clone.FeatureValues, dtree.featureName)
+				}
+				insts = append(insts, clone)
+			}
+			if parallel && len(insts) >= parallelTrainMinInstances {
+				select {
+				case trainWorkers <- struct{}{}:
+					wg.Add(1)
+					go func(i int, insts []*Instance) {
+						defer func() { <-trainWorkers; wg.Done() }()
+						subtrees[i], errs[i] = limitedTrain(ClassifiedDataSet{Instances: insts}, opts, depth+1, true)
+					}(i, insts)
+					continue
+				default: // Every worker is busy, so train it here
+				}
+			}
+			subtrees[i], errs[i] = limitedTrain(ClassifiedDataSet{Instances: insts}, opts, depth+1, parallel)
+		}
+		wg.Wait()
+		for i, group := range groups {
+			if errs[i] != nil {
+				return nil, errs[i]
+			}
+			for _, k := range group {
+				dtree.nextDecisions[k] = subtrees[i]
 			}
 		}
 		return dtree, nil
 	}
 }
 
+// Groups the feature values of a split so there are at most maxChildren groups, in ascending order of value. The
+// maxChildren-1 most populated values keep a group of their own and the rest are merged into the last group. Ties
+// in population keep the smaller value. A maxChildren below 2 gives every value its own group.
+func groupFeatureValues(buckets map[Feature][]*Instance, maxChildren int) [][]Feature {
+	values := make([]Feature, 0, len(buckets))
+	for featureValue := range buckets {
+		values = append(values, featureValue)
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	if maxChildren < 2 || len(values) <= maxChildren {
+		groups := make([][]Feature, len(values))
+		for i, featureValue := range values {
+			groups[i] = []Feature{featureValue}
+		}
+		return groups
+	}
+
+	byPopulation := append([]Feature{}, values...)
+	sort.SliceStable(byPopulation, func(i, j int) bool { return len(buckets[byPopulation[i]]) > len(buckets[byPopulation[j]]) })
+	kept := make(map[Feature]bool, maxChildren-1)
+	for _, featureValue := range byPopulation[:maxChildren-1] {
+		kept[featureValue] = true
+	}
+	groups := make([][]Feature, 0, maxChildren)
+	var other []Feature
+	for _, featureValue := range values {
+		if kept[featureValue] {
+			groups = append(groups, []Feature{featureValue})
+		} else {
+			other = append(other, featureValue)
+		}
+	}
+	return append(groups, other)
+}
+
+// What a pruning pass did: the number of decision nodes it collapsed into leaves, and the validation error rate
+// before and after.
+type PruneStats struct {
+	CollapsedNodes int
+	ErrorBefore    float64
+	ErrorAfter     float64
+}
+
 // Prune a trained Decision tree using the Reduced Error Prune method. A set of labeled instances must be provided
-// to prune with.
-func (thisTree *Decision) ReducedErrorPrune(validate ClassifiedDataSet) error {
+// to prune with, and an empty one is an error. Collapsed subtrees become leaves predicting the majority target of
+// their training instances, like the other pruning methods, so branches no validation instance reaches keep a
+// sensible prediction. Reports what the pruning did, so it can be checked that it helped.
+func (thisTree *Decision) ReducedErrorPrune(validate ClassifiedDataSet) (PruneStats, error) {
+	var stats PruneStats
+	var err error
+	if stats.ErrorBefore, err = thisTree.CalculateError(validate); err != nil {
+		return stats, err
+	}
+	stats.ErrorAfter = stats.ErrorBefore
 	// Use a stack of Decision nodes and applicable subset of the ClassifiedDataSet
 	treeStack, dsStack := []*Decision{thisTree}, [][]*Instance{validate.Instances};
 	for ; len(treeStack) > 0; {
@@ -168,66 +427,667 @@ func (thisTree *Decision) ReducedErrorPrune(validate ClassifiedDataSet) error {
 			continue
 		}
 
-		// Sort instances into buckets of feature value
+		// Sort instances into buckets of feature value. Instances missing the value are classified by a vote across
+		// every branch, so they go in every bucket.
 		featureValueToInsts := make(map[Feature][]*Instance, len(curDS))
+		var missing []*Instance
 		for _, inst := range curDS {
-			instances, ok := featureValueToInsts[inst.FeatureValues[curTree.featureName]]
+			featureValue := inst.FeatureValues[curTree.featureName]
+			if featureValue == FeatureMissing {
+				missing = append(missing, inst)
+				continue
+			}
+			key := curTree.branchKey(featureValue)
+			instances, ok := featureValueToInsts[key]
 			if !ok {
 				instances = make([]*Instance, 0)
 			}
-			featureValueToInsts[inst.FeatureValues[curTree.featureName]] = append(instances, inst)
+			featureValueToInsts[key] = append(instances, inst)
 		}
 
-		// Iterate over all subtrees, attempting to replace them with output nodes for the most popular instance type.
-		// If the error isn't reduced, then the subtree is added to the stack so prune attempts can be done on its own
-		// subtrees.
-		for featureValue, subTree := range curTree.nextDecisions {
-			applicableInstances := featureValueToInsts[featureValue]
-			prevError, err := thisTree.CalculateError(validate)
-			if err != nil {
-				return err
+		// Iterate over all subtrees in order of feature value, attempting to replace them with output nodes for their
+		// training majority. If the error isn't reduced, then the subtree is added to the stack so prune attempts can
+		// be done on its own subtrees. Each attempt is judged against the error of the tree with every collapse
+		// accepted so far, so the order is fixed to prune the same way every time.
+		for _, featureValue := range sortedFeatureValues(curTree.nextDecisions) {
+			subTree := curTree.nextDecisions[featureValue]
+			applicableInstances := append(featureValueToInsts[featureValue], missing...)
+			leaf := subTree.prunedLeaf(applicableInstances)
+			if leaf == nil { // Nothing to predict, so the subtree stays
+				treeStack = append(treeStack, subTree)
+				dsStack = append(dsStack, applicableInstances)
+				continue
 			}
-			curTree.nextDecisions[featureValue] = &Decision{isOutput: true, outputValue: mostPopularTarget(applicableInstances)}
+			curTree.nextDecisions[featureValue] = leaf
 			postError, err := thisTree.CalculateError(validate)
-			if postError > prevError { // An output decision is bad here, replace with original decision and push to stack
+			if err != nil {
+				return stats, err
+			}
+			if postError > stats.ErrorAfter { // An output decision is bad here, replace with original decision and push to stack
 				curTree.nextDecisions[featureValue] = subTree
 				treeStack = append(treeStack, subTree)
 				dsStack = append(dsStack, applicableInstances)
+				continue
+			}
+			stats.ErrorAfter = postError // The new baseline
+			if !subTree.isOutput {
+				stats.CollapsedNodes++
 			}
 		}
 	}
+	return stats, nil
+}
+
+// Builds the output node a subtree would be collapsed into: one predicting the majority target of the training
+// instances that reached it, keeping their distribution. Hand-built subtrees have no such counts, so they predict
+// the most popular target of the validation instances reaching them instead, and can't be collapsed without any.
+func (dtree *Decision) prunedLeaf(insts []*Instance) *Decision {
+	if len(dtree.distribution) > 0 {
+		return &Decision{isOutput: true, outputValue: dtree.MajorityTarget(), distribution: dtree.distribution}
+	} else if len(insts) > 0 {
+		return &Decision{isOutput: true, outputValue: mostPopularTarget(insts)}
+	}
 	return nil
 }
 
 // Calculates the error the provided decision tree encounters in classifying the provided pre-classified dataset.
+// Weighted instances count for their weight. An empty dataset has no error rate, so is an error.
 func (dtree *Decision) CalculateError(ds ClassifiedDataSet) (float64, error) {
+	if len(ds.Instances) == 0 {
+		return 1.0, errors.New("no instances provided")
+	}
 	wrongClassifications := 0.0
 	for _, inst := range ds.Instances { // Classify each instance
-		correctTargetValue := inst.TargetValue // Keep track of original value
-		if err := dtree.Classify(inst); err != nil {
+		if target, err := dtree.Predict(inst); err != nil {
 			return 1.0, err
-		} else if correctTargetValue != inst.TargetValue {
-			wrongClassifications++
+		} else if target != inst.TargetValue {
+			wrongClassifications += inst.weight()
 		}
-		inst.TargetValue = correctTargetValue // Restore original value
 	}
-	return wrongClassifications / float64(len(ds.Instances)), nil
+	return wrongClassifications / totalWeight(ds.Instances), nil
+}
+
+// Tallies how the tree classifies a dataset, mapping each actual target to the weight of the instances predicted
+// as each target. Unweighted instances count once each, so the tallies are plain counts. The instances aren't
+// modified.
+func (dtree *Decision) ConfusionMatrix(ds ClassifiedDataSet) (map[Target]map[Target]float64, error) {
+	matrix := make(map[Target]map[Target]float64)
+	for _, inst := range ds.Instances {
+		target, err := dtree.Predict(inst)
+		if err != nil {
+			return nil, err
+		}
+		if matrix[inst.TargetValue] == nil {
+			matrix[inst.TargetValue] = make(map[Target]float64)
+		}
+		matrix[inst.TargetValue][target] += inst.weight()
+	}
+	return matrix, nil
+}
+
+// Summary metrics of a tree's predictions on a dataset, with precision, recall and F1 for one positive target.
+type Scores struct {
+	Accuracy  float64
+	Precision float64
+	Recall    float64
+	F1        float64
+}
+
+// Classifies the dataset once and derives the accuracy, and the precision, recall and F1 of positive, from the
+// confusion matrix, so weighted instances count for their weight and the accuracy is 1 minus CalculateError. The
+// instances aren't modified.
+func (dtree *Decision) Score(ds ClassifiedDataSet, positive Target) (Scores, error) {
+	matrix, err := dtree.ConfusionMatrix(ds)
+	if err != nil {
+		return Scores{}, err
+	}
+	return Scores{Accuracy(matrix), Precision(matrix, positive), Recall(matrix, positive), F1(matrix, positive)}, nil
+}
+
+// Calculates the fraction of instances predicted correctly, from a confusion matrix. Zero for an empty matrix.
+func Accuracy(matrix map[Target]map[Target]float64) float64 {
+	correct, total := 0.0, 0.0
+	for actual, predictions := range matrix {
+		for predicted, count := range predictions {
+			if predicted == actual {
+				correct += count
+			}
+			total += count
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return correct / total
+}
+
+// Calculates the fraction of instances predicted as class that actually are, from a confusion matrix. Zero when
+// nothing was predicted as class.
+func Precision(matrix map[Target]map[Target]float64, class Target) float64 {
+	predicted := 0.0
+	for _, predictions := range matrix {
+		predicted += predictions[class]
+	}
+	if predicted == 0 {
+		return 0
+	}
+	return matrix[class][class] / predicted
+}
+
+// Calculates the fraction of instances of class that were predicted as such, from a confusion matrix. Zero when
+// there are no instances of class.
+func Recall(matrix map[Target]map[Target]float64, class Target) float64 {
+	actual := 0.0
+	for _, count := range matrix[class] {
+		actual += count
+	}
+	if actual == 0 {
+		return 0
+	}
+	return matrix[class][class] / actual
+}
+
+// Calculates the harmonic mean of the precision and recall of class, from a confusion matrix.
+func F1(matrix map[Target]map[Target]float64, class Target) float64 {
+	precision, recall := Precision(matrix, class), Recall(matrix, class)
+	if precision+recall == 0 {
+		return 0
+	}
+	return 2 * precision * recall / (precision + recall)
+}
+
+// Calculates the Brier score of the tree's probabilities on a dataset: the mean squared difference between the
+// predicted probability of the positive (true) target and the actual 0 or 1 outcome. Lower is better, and unlike
+// log-loss a single overconfident mistake can't dominate it.
+func (dtree *Decision) BrierScore(ds ClassifiedDataSet) (float64, error) {
+	if len(ds.Instances) == 0 {
+		return 0, errors.New("no instances provided")
+	}
+	score := 0.0
+	for _, inst := range ds.Instances {
+		probs, err := dtree.ClassifyProbabilities(inst)
+		if err != nil {
+			return 0, err
+		}
+		outcome := 0.0
+		if inst.TargetValue == Target(true) {
+			outcome = 1
+		}
+		diff := probs[Target(true)] - outcome
+		score += diff * diff
+	}
+	return score / float64(len(ds.Instances)), nil
+}
+
+// Checks whether the tree classifies every instance of a dataset correctly, i.e. whether CalculateError would be
+// zero, stopping at the first mistake. The instances aren't modified.
+func (dtree *Decision) IsConsistent(ds ClassifiedDataSet) (bool, error) {
+	for _, inst := range ds.Instances {
+		if target, err := dtree.Predict(inst); err != nil {
+			return false, err
+		} else if target != inst.TargetValue {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Calculates the mean number of decisions taken to classify each instance of a dataset, a proxy for the tree's
+// runtime classification cost on that workload. An instance missing a value follows several paths rather than one,
+// so it is an error, as for Predict without the vote. The instances aren't modified.
+func (dtree *Decision) AverageDepth(ds ClassifiedDataSet) (float64, error) {
+	if len(ds.Instances) == 0 {
+		return 0, errors.New("no instances provided")
+	}
+	hops := 0
+	for _, inst := range ds.Instances {
+		if _, err := dtree.leafFor(inst.FeatureValues); err != nil {
+			return 0, err
+		}
+		for node := dtree; !node.isOutput; node = node.nextDecisions[node.branchKey(inst.FeatureValues[node.featureName])] {
+			hops++
+		}
+	}
+	return float64(hops) / float64(len(ds.Instances)), nil
 }
 
 // Attempt to classify a provided instance of data. The classification is set in the instance's TargetValue field.
 func (dtree *Decision) Classify(inst *Instance) error {
+	target, err := dtree.Predict(inst)
+	if err != nil {
+		return err
+	}
+	inst.TargetValue = target // Previous value is overwritten
+	return nil
+}
+
+// Classifies a provided instance without modifying it, returning the predicted target instead. Since nothing is
+// written, any number of goroutines can predict with the same tree and instances at once.
+func (dtree *Decision) Predict(inst *Instance) (Target, error) {
+	return dtree.predict(inst.FeatureValues)
+}
+
+// Classifies a set of feature values, voting across the branches of any missing ones. Every other way of
+// predicting a target goes through here, so they all agree with Predict.
+func (dtree *Decision) predict(features map[string]Feature) (Target, error) {
+	leaf, err := dtree.leafFor(features)
+	if err != nil && hasMissingValue(features) { // Vote across the branches of the missing values
+		probs, err := dtree.missingProbabilities(features)
+		if err != nil {
+			return nil, err
+		}
+		return mostVotedTarget(probs), nil
+	} else if err != nil {
+		return nil, err
+	}
+	return leaf.outputValue, nil
+}
+
+// Attempt to classify a provided instance of data like Classify, but rather than failing on a feature value the
+// tree never saw at some node, or a missing feature, fall back to the majority target of the training instances
+// that reached that node. Only nodes without a recorded distribution, like hand-built ones, still error.
+func (dtree *Decision) ClassifyWithFallback(inst *Instance) error {
+	for !dtree.isOutput {
+		thisValue, ok := inst.FeatureValues[dtree.featureName]
+		nextDecision, found := dtree.nextDecision(thisValue)
+		if ok && found {
+			dtree = nextDecision
+			continue
+		} else if len(dtree.distribution) == 0 {
+			return errors.New(fmt.Sprint("no decision node or fallback corresponding to instance value of ", thisValue, " for ", dtree.featureName))
+		}
+		inst.TargetValue = dtree.MajorityTarget()
+		return nil
+	}
+	inst.TargetValue = dtree.outputValue
+	return nil
+}
+
+// Classifies a set of feature values without allocating, writing the prediction into the caller-owned result.
+// Meant for hot serving loops where result is reused across calls. Only features with missing values allocate.
+func (dtree *Decision) PredictInto(features map[string]Feature, result *Target) error {
+	target, err := dtree.predict(features)
+	if err != nil {
+		return err
+	}
+	*result = target
+	return nil
+}
+
+// Classifies a provided instance without modifying it, also naming the feature on its path whose split had the
+// greatest information gain. That feature was the most decisive one for this particular prediction. An instance
+// missing the value of a split's feature has no single path to explain, so it is an error.
+func (dtree *Decision) ClassifyExplain(inst *Instance) (target Target, featureName string, err error) {
+	greatestGain, greatestFeatureName := -1.0, ""
+	for !dtree.isOutput {
+		if dtree.gain > greatestGain {
+			greatestGain, greatestFeatureName = dtree.gain, dtree.featureName
+		}
+		thisValue, ok := inst.FeatureValues[dtree.featureName]
+		if !ok {
+			return target, "", errors.New(fmt.Sprint("no decision node for feature ", dtree.featureName))
+		} else if thisValue == FeatureMissing {
+			return target, "", errors.New(fmt.Sprint("missing value for feature ", dtree.featureName))
+		}
+		nextDecision, ok := dtree.nextDecision(thisValue)
+		if !ok {
+			return target, "", errors.New(fmt.Sprint("no decision node corresponding to instance value of ", thisValue, " for ", dtree.featureName))
+		}
+		dtree = nextDecision
+	}
+	return dtree.outputValue, greatestFeatureName, nil
+}
+
+// Determines the class distribution at the output node a provided instance reaches, i.e. the fraction of the
+// training instances there with each target. The instance isn't modified.
+func (dtree *Decision) ClassifyProbabilities(inst *Instance) (map[Target]float64, error) {
+	leaf, err := dtree.leafFor(inst.FeatureValues)
+	if err != nil && hasMissingValue(inst.FeatureValues) {
+		return dtree.missingProbabilities(inst.FeatureValues)
+	} else if err != nil {
+		return nil, err
+	}
+	return leaf.probabilities(), nil
+}
+
+// Determines the probability of each target for a provided instance, the counterpart of Predict for
+// ClassifyProbabilities. The instance isn't modified.
+func (dtree *Decision) PredictProba(inst *Instance) (map[Target]float64, error) {
+	return dtree.ClassifyProbabilities(inst)
+}
+
+// Predicts a target for a provided instance like Predict, also reporting whether the prediction is confident:
+// whether the share of the training instances at its leaf having the predicted target is at least minConfidence.
+// Callers can abstain from unconfident predictions, e.g. to route them to a person instead. The instance isn't
+// modified.
+func (dtree *Decision) PredictWithConfidence(inst *Instance, minConfidence float64) (Target, bool, error) {
+	target, err := dtree.Predict(inst)
+	if err != nil {
+		return nil, false, err
+	}
+	probs, err := dtree.ClassifyProbabilities(inst)
+	if err != nil {
+		return nil, false, err
+	}
+	return target, probs[target] >= minConfidence, nil
+}
+
+// Classifies a provided instance without modifying it, letting the caller settle ties between equally probable
+// targets at the leaf, such as to prefer the safer class. tieBreak receives the tied targets in
+// ascending order. When it is nil the smallest tied target wins.
+func (dtree *Decision) ClassifyWith(inst *Instance, tieBreak func(tied []Target) Target) (Target, error) {
+	probs, err := dtree.ClassifyProbabilities(inst)
+	if err != nil {
+		var target Target
+		return target, err
+	}
+	highestProb := 0.0
+	var tied []Target
+	for target, prob := range probs {
+		if prob > highestProb {
+			highestProb, tied = prob, append(tied[:0], target)
+		} else if prob == highestProb {
+			tied = append(tied, target)
+		}
+	}
+	sort.Slice(tied, func(i, j int) bool { return targetLess(tied[i], tied[j]) })
+	if len(tied) > 1 && tieBreak != nil {
+		return tieBreak(tied), nil
+	}
+	return tied[0], nil
+}
+
+// Normalizes a node's target counts into probabilities. Nodes without counts, e.g. from hand-built trees, are
+// certain of their output value.
+func (dtree *Decision) probabilities() map[Target]float64 {
+	total := 0.0
+	for _, count := range dtree.distribution {
+		total += count
+	}
+	if total == 0 {
+		return map[Target]float64{dtree.outputValue: 1}
+	}
+	probs := make(map[Target]float64, len(dtree.distribution))
+	for target, count := range dtree.distribution {
+		probs[target] = count / total
+	}
+	return probs
+}
+
+// Finds the output node a provided instance is classified by, so that its metadata can be read. The instance
+// isn't modified.
+func (dtree *Decision) ClassifyLeaf(inst *Instance) (*Decision, error) {
+	return dtree.leafFor(inst.FeatureValues)
+}
+
+// Collects every output node a partially-specified instance could reach, branching on each feature missing from
+// partial as though it could take any value. Leaves are listed in order of feature value.
+func (dtree *Decision) ReachableLeaves(partial map[string]Feature) []*Decision {
 	if dtree.isOutput {
-		inst.TargetValue = dtree.outputValue // Previous value is overwritten
+		return []*Decision{dtree}
+	} else if thisValue, ok := partial[dtree.featureName]; ok {
+		if nextDecision, ok := dtree.nextDecision(thisValue); ok {
+			return nextDecision.ReachableLeaves(partial)
+		}
 		return nil
-	} else if thisValue, ok := inst.FeatureValues[dtree.featureName]; ok {
-		if nextDecision, ok := dtree.nextDecisions[thisValue]; ok {
-			return nextDecision.Classify(inst)
-		} else {
-			return errors.New(fmt.Sprint("no decision node corresponding to instance value of ", thisValue, " for ", dtree.featureName))
+	}
+	var leaves []*Decision
+	for _, featureValue := range sortedFeatureValues(dtree.nextDecisions) {
+		leaves = append(leaves, dtree.nextDecisions[featureValue].ReachableLeaves(partial)...)
+	}
+	return leaves
+}
+
+// Follows the feature values down the tree to an output node.
+func (dtree *Decision) leafFor(features map[string]Feature) (*Decision, error) {
+	for !dtree.isOutput {
+		thisValue, ok := features[dtree.featureName]
+		if !ok {
+			return nil, errors.New(fmt.Sprint("no decision node for feature ", dtree.featureName))
+		}
+		if thisValue == FeatureMissing {
+			return nil, errors.New(fmt.Sprint("missing value for feature ", dtree.featureName))
+		}
+		nextDecision, ok := dtree.nextDecision(thisValue)
+		if !ok {
+			return nil, errors.New(fmt.Sprint("no decision node corresponding to instance value of ", thisValue, " for ", dtree.featureName))
+		}
+		dtree = nextDecision
+	}
+	return dtree, nil
+}
+
+// Determines the class distribution for features that may be missing values. Where the value of a node's feature
+// is missing, every branch is followed and their distributions are averaged, weighted by the training support of
+// each branch. Branches of hand-built trees have no support, so count equally.
+func (dtree *Decision) missingProbabilities(features map[string]Feature) (map[Target]float64, error) {
+	if dtree.isOutput {
+		return dtree.probabilities(), nil
+	}
+	thisValue, ok := features[dtree.featureName]
+	if !ok {
+		return nil, errors.New(fmt.Sprint("no decision node for feature ", dtree.featureName))
+	} else if thisValue != FeatureMissing {
+		nextDecision, ok := dtree.nextDecision(thisValue)
+		if !ok {
+			return nil, errors.New(fmt.Sprint("no decision node corresponding to instance value of ", thisValue, " for ", dtree.featureName))
+		}
+		return nextDecision.missingProbabilities(features)
+	}
+
+	probs := make(map[Target]float64)
+	total := 0.0
+	for _, subtree := range dtree.uniqueChildren() {
+		subtreeProbs, err := subtree.missingProbabilities(features)
+		if err != nil {
+			return nil, err
+		}
+		support := 0.0
+		for _, count := range subtree.distribution {
+			support += count
+		}
+		if support == 0 {
+			support = 1
+		}
+		for target, prob := range subtreeProbs {
+			probs[target] += support * prob
+		}
+		total += support
+	}
+	for target := range probs {
+		probs[target] /= total
+	}
+	return probs, nil
+}
+
+// Checks whether any of the features is FeatureMissing.
+func hasMissingValue(features map[string]Feature) bool {
+	for _, value := range features {
+		if value == FeatureMissing {
+			return true
+		}
+	}
+	return false
+}
+
+// Filters out the instances missing a value for the feature, returning insts itself when none are.
+func knownInstances(insts []*Instance, featureName string) []*Instance {
+	for i, inst := range insts {
+		if inst.FeatureValues[featureName] == FeatureMissing {
+			known := append([]*Instance{}, insts[:i]...)
+			for _, inst := range insts[i+1:] {
+				if inst.FeatureValues[featureName] != FeatureMissing {
+					known = append(known, inst)
+				}
+			}
+			return known
+		}
+	}
+	return insts
+}
+
+// Finds the child a feature value leads to.
+func (dtree *Decision) nextDecision(value Feature) (*Decision, bool) {
+	if value == FeatureMissing { // Would otherwise be routed above any threshold
+		return nil, false
+	}
+	nextDecision, ok := dtree.nextDecisions[dtree.branchKey(value)]
+	return nextDecision, ok
+}
+
+// Maps a feature value to the key of the child it leads to: the value itself, or for a threshold split 0 when
+// the value is at most the threshold and 1 otherwise.
+func (dtree *Decision) branchKey(value Feature) Feature {
+	if !dtree.continuous {
+		return value
+	} else if value <= dtree.threshold {
+		return 0
+	}
+	return 1
+}
+
+// Predicts the target at every point of the grid spanned by two features' domains, such as for plotting the
+// decision surface of a 2-feature model. Points the tree can't classify, like values it never saw, are left out,
+// while a FeatureMissing in a domain is predicted by the vote Predict takes.
+func (dtree *Decision) DecisionRegions(featureA, featureB string, domainA, domainB []Feature) map[[2]Feature]Target {
+	regions := make(map[[2]Feature]Target, len(domainA)*len(domainB))
+	features := make(map[string]Feature, 2)
+	for _, a := range domainA {
+		for _, b := range domainB {
+			features[featureA], features[featureB] = a, b
+			if target, err := dtree.predict(features); err == nil {
+				regions[[2]Feature{a, b}] = target
+			}
+		}
+	}
+	return regions
+}
+
+// The target an output node classifies instances as.
+func (dtree *Decision) OutputValue() Target {
+	return dtree.outputValue
+}
+
+// Training statistics of a tree node, shared by confidence, smoothing and pruning calculations.
+type Stats struct {
+	Support         float64            // Total weight of the training instances reaching the node
+	Distribution    map[Target]float64 // Weight of those instances with each target
+	RunnerUp        Target             // Best supported target other than the node's output value
+	RunnerUpSupport float64            // Weight behind RunnerUp, 0 when every instance agreed with the output
+}
+
+// Reports the support and target distribution recorded when the node was trained, along with the runner-up to
+// its output. Ties for runner-up go to the smaller target. Hand-built nodes have no recorded support.
+func (dtree *Decision) Stats() Stats {
+	stats := Stats{Distribution: make(map[Target]float64, len(dtree.distribution))}
+	for target, count := range dtree.distribution {
+		stats.Distribution[target] = count
+		stats.Support += count
+		if target == dtree.outputValue || count < stats.RunnerUpSupport {
+			continue
+		}
+		if count > stats.RunnerUpSupport || targetLess(target, stats.RunnerUp) {
+			stats.RunnerUp, stats.RunnerUpSupport = target, count
+		}
+	}
+	return stats
+}
+
+// Finds the most common target among the training instances that reached the node, ties going to the smaller
+// target. Every trained node has one, including decision nodes, which makes it the fallback for instances that
+// can't go further down and the prediction of a pruned node. Hand-built nodes without counts give their output
+// value, which is nil for a decision node.
+func (dtree *Decision) MajorityTarget() Target {
+	majority, highestCount := dtree.outputValue, -1.0
+	for target, count := range dtree.distribution {
+		if count > highestCount || count == highestCount && targetLess(target, majority) {
+			majority, highestCount = target, count
 		}
-	} else {
-		return errors.New(fmt.Sprint("no decision node for feature ", dtree.featureName))
 	}
+	return majority
+}
+
+// Scores how much each feature the tree splits on contributes to its decisions: the information gain of each of
+// the feature's splits, weighted by the training instances reaching the split, summed and normalized so the scores
+// add up to 1. Features the tree doesn't split on are left out, and a tree without any gain has no scores.
+func (dtree *Decision) FeatureImportances() map[string]float64 {
+	importances := make(map[string]float64)
+	total := 0.0
+	for _, node := range dtree.decisionNodes() {
+		contribution := node.gain * node.Stats().Support
+		importances[node.featureName] += contribution
+		total += contribution
+	}
+	if total == 0 {
+		return map[string]float64{}
+	}
+	for featureName := range importances {
+		importances[featureName] /= total
+	}
+	return importances
+}
+
+// Calculates the number of decisions on the longest path from the node to a leaf. A single output node has depth
+// zero.
+func (dtree *Decision) Depth() int {
+	depth := 0
+	for _, subtree := range dtree.nextDecisions {
+		if subtreeDepth := subtree.Depth() + 1; subtreeDepth > depth {
+			depth = subtreeDepth
+		}
+	}
+	return depth
+}
+
+// Counts the nodes of the tree, decision and output alike. Nodes shared by several feature values are counted once.
+func (dtree *Decision) NodeCount() int {
+	count := 1
+	for _, subtree := range dtree.uniqueChildren() {
+		count += subtree.NodeCount()
+	}
+	return count
+}
+
+// Counts the output nodes of the tree. Nodes shared by several feature values are counted once.
+func (dtree *Decision) LeafCount() int {
+	if dtree.isOutput {
+		return 1
+	}
+	count := 0
+	for _, subtree := range dtree.uniqueChildren() {
+		count += subtree.LeafCount()
+	}
+	return count
+}
+
+// Lists the node's distinct children in order of feature value, once each however many values lead to them.
+func (dtree *Decision) uniqueChildren() []*Decision {
+	var children []*Decision
+	seen := make(map[*Decision]bool, len(dtree.nextDecisions))
+	for _, featureValue := range sortedFeatureValues(dtree.nextDecisions) {
+		if subtree := dtree.nextDecisions[featureValue]; !seen[subtree] {
+			seen[subtree] = true
+			children = append(children, subtree)
+		}
+	}
+	return children
+}
+
+// Attaches metadata under key to every output node of the tree, such as a recommended action for the outcome.
+// fn is called once per leaf to produce its value.
+func (dtree *Decision) SetLeafMeta(key string, fn func(leaf *Decision) interface{}) {
+	dtree.walkLeaves(nil, func(_ []FeatureStep, leaf *Decision) {
+		if leaf.leafMeta == nil {
+			leaf.leafMeta = make(map[string]interface{})
+		}
+		leaf.leafMeta[key] = fn(leaf)
+	})
+}
+
+// Retrieves metadata attached to an output node with SetLeafMeta.
+func (dtree *Decision) Meta(key string) (interface{}, bool) {
+	value, ok := dtree.leafMeta[key]
+	return value, ok
 }
 
 // Checks if all instances provided have the same target value
@@ -240,21 +1100,77 @@ func instancesIdentical(insts []*Instance) bool {
 	return true
 }
 
-// Identifies the most 'popular' target value in the slice of instances passed
-func mostPopularTarget(insts []*Instance) Target {
-	targetCounts := make(map[Target]int, len(insts))
-	highestCount := 0
-	var highestTarget Target
+// Checks whether any of the instances has a value, possibly FeatureMissing, for the named feature.
+func hasFeature(insts []*Instance, featureName string) bool {
 	for _, inst := range insts {
-		count, _ := targetCounts[inst.TargetValue]
-		count++
-		targetCounts[inst.TargetValue] = count
-		if count > highestCount {
-			highestCount = count
-			highestTarget = inst.TargetValue
+		if _, ok := inst.FeatureValues[featureName]; ok {
+			return true
 		}
 	}
-	return highestTarget
+	return false
+}
+
+// Checks whether every instance has the same feature values, so any split would send them all down one branch.
+func featuresIdentical(insts []*Instance) bool {
+	for i := 1; i < len(insts); i++ {
+		if !reflect.DeepEqual(insts[i].FeatureValues, insts[0].FeatureValues) {
+			return false
+		}
+	}
+	return true
+}
+
+// Counts the instances with each target value.
+func targetDistribution(insts []*Instance) map[Target]float64 {
+	distribution := make(map[Target]float64)
+	for _, inst := range insts {
+		distribution[inst.TargetValue] += inst.weight()
+	}
+	return distribution
+}
+
+// Orders target values for deterministic tie-breaking and output: false before true, numbers and strings in
+// ascending order, and otherwise by kind then printed form. nil comes first.
+func targetLess(a, b Target) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if !va.IsValid() || !vb.IsValid() {
+		return !va.IsValid() && vb.IsValid()
+	}
+	if kindClass(va.Kind()) != kindClass(vb.Kind()) {
+		return kindClass(va.Kind()) < kindClass(vb.Kind())
+	}
+	switch kindClass(va.Kind()) {
+	case reflect.Bool:
+		return !va.Bool() && vb.Bool()
+	case reflect.Int:
+		return va.Int() < vb.Int()
+	case reflect.Uint:
+		return va.Uint() < vb.Uint()
+	case reflect.Float64:
+		return va.Float() < vb.Float()
+	case reflect.String:
+		return va.String() < vb.String()
+	}
+	return fmt.Sprintf("%#v", a) < fmt.Sprintf("%#v", b)
+}
+
+// Groups the sized variants of a kind, e.g. int8 and int64, under the one kind targetLess compares them by.
+func kindClass(kind reflect.Kind) reflect.Kind {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflect.Int
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return reflect.Uint
+	case reflect.Float32, reflect.Float64:
+		return reflect.Float64
+	}
+	return kind
+}
+
+// Identifies the most 'popular' target value in the slice of instances passed, by total weight. Ties go to the
+// smaller target, so the result doesn't depend on the order of the instances.
+func mostPopularTarget(insts []*Instance) Target {
+	return mostVotedTarget(targetDistribution(insts))
 }
 
 // A BestFeature function that uses information gain to determine the best feature.
@@ -262,8 +1178,9 @@ func BestFeatureInformationGain(ds ClassifiedDataSet) string {
 	greatestInfoGain := 0.0
 	greatestFeatureName := ""
 	for featureName := range ds.Instances[0].FeatureValues {
-		infoGain := infoGainOfFeature(ds, featureName)
-		if infoGain > greatestInfoGain { // Determine feature with greatest info gain
+		infoGain := InformationGain(ds, featureName)
+		if infoGain > greatestInfoGain || // Determine feature with greatest info gain
+			infoGain == greatestInfoGain && greatestFeatureName != "" && featureName < greatestFeatureName { // Ties go to the smallest name
 			greatestInfoGain = infoGain
 			greatestFeatureName = featureName
 		}
@@ -271,45 +1188,270 @@ func BestFeatureInformationGain(ds ClassifiedDataSet) string {
 	return greatestFeatureName
 }
 
+// Creates a BestFeature function that picks by information gain like BestFeatureInformationGain, but makes a leaf
+// instead of splitting when the best gain is below minGain bits. Unlike Options.MinImpurityDecreaseFraction, the
+// threshold is absolute rather than relative to the node's entropy.
+func BestFeatureInformationGainThreshold(minGain float64) BestFeatureFunc {
+	return func(ds ClassifiedDataSet) string {
+		featureName := BestFeatureInformationGain(ds)
+		if featureName != "" && InformationGain(ds, featureName) < minGain {
+			return ""
+		}
+		return featureName
+	}
+}
+
 var _ BestFeatureFunc = BestFeatureInformationGain
 
-// Determines the information gain of a specified feature for a ClassifiedDataSet.
-func infoGainOfFeature(ds ClassifiedDataSet, featureName string) float64 {
+// A BestFeature function that picks the feature with the greatest uncertainty coefficient (Theil's U): its
+// information gain as a fraction of the node's entropy. A pure node has no uncertainty to remove, so no feature is
+// picked.
+func BestFeatureUncertaintyCoefficient(ds ClassifiedDataSet) string {
+	parentEntropy := Entropy(ds.Instances)
+	if parentEntropy == 0 {
+		return ""
+	}
+	greatestCoefficient := 0.0
+	greatestFeatureName := ""
+	for featureName := range ds.Instances[0].FeatureValues {
+		coefficient := InformationGain(ds, featureName) / parentEntropy
+		if coefficient > greatestCoefficient ||
+			coefficient == greatestCoefficient && greatestFeatureName != "" && featureName < greatestFeatureName { // Ties go to the smallest name
+			greatestCoefficient = coefficient
+			greatestFeatureName = featureName
+		}
+	}
+	return greatestFeatureName
+}
+
+var _ BestFeatureFunc = BestFeatureUncertaintyCoefficient
+
+// A BestFeature function for the OneR baseline: picks the feature whose values, each predicting the majority target
+// of the instances with that value, misclassify the least weight of instances. Instances missing the feature count
+// against the node's majority target. Ties go to the smallest name, and no feature is picked unless it makes fewer
+// mistakes than predicting the node's majority target for every instance.
+func BestFeatureOneR(ds ClassifiedDataSet) string {
+	leastErrors := oneRErrors(ds.Instances)
+	leastFeatureName := ""
+	for featureName := range ds.Instances[0].FeatureValues {
+		wrong := oneRErrorsOfFeature(ds, featureName)
+		if wrong < leastErrors ||
+			wrong == leastErrors && leastFeatureName != "" && featureName < leastFeatureName { // Ties go to the smallest name
+			leastErrors = wrong
+			leastFeatureName = featureName
+		}
+	}
+	return leastFeatureName
+}
+
+var _ BestFeatureFunc = BestFeatureOneR
+
+// Trains the OneR baseline: a single split on the feature picked by BestFeatureOneR, each value predicting its
+// majority target. Useful as a point of comparison for a full tree.
+func TrainOneR(ds ClassifiedDataSet) (*Decision, error) {
+	return LimitedTrain(ds, BestFeatureOneR, 1)
+}
+
+// Calculates the weight of the instances that predicting their majority target gets wrong.
+func oneRErrors(insts []*Instance) float64 {
+	majority := 0.0
+	for _, count := range targetDistribution(insts) {
+		majority = math.Max(majority, count)
+	}
+	return totalWeight(insts) - majority
+}
+
+// Calculates the weight of the instances misclassified by a rule on featureName alone, as in BestFeatureOneR.
+func oneRErrorsOfFeature(ds ClassifiedDataSet, featureName string) float64 {
+	featureValueToInsts := make(map[Feature][]*Instance)
+	var missing []*Instance
+	for _, inst := range ds.Instances {
+		if featureValue := inst.FeatureValues[featureName]; featureValue == FeatureMissing {
+			missing = append(missing, inst)
+		} else {
+			featureValueToInsts[featureValue] = append(featureValueToInsts[featureValue], inst)
+		}
+	}
+	featureValues := make([]Feature, 0, len(featureValueToInsts))
+	for featureValue := range featureValueToInsts {
+		featureValues = append(featureValues, featureValue)
+	}
+	sort.Slice(featureValues, func(i, j int) bool { return featureValues[i] < featureValues[j] })
+	wrong := 0.0
+	for _, featureValue := range featureValues { // In order, so rounding is the same every time
+		wrong += oneRErrors(featureValueToInsts[featureValue])
+	}
+	if len(missing) > 0 {
+		wrong += totalWeight(missing) - targetDistribution(missing)[mostPopularTarget(ds.Instances)]
+	}
+	return wrong
+}
+
+// Determines the information gain, in bits, of a specified feature for a ClassifiedDataSet: how much splitting on
+// it reduces the entropy of the targets. Instances count for their weight, and as in C4.5 the gain among instances
+// with a value for the feature is scaled by their share of the weight. Useful for writing custom BestFeatureFuncs.
+func InformationGain(ds ClassifiedDataSet, featureName string) float64 {
+	if known := knownInstances(ds.Instances, featureName); len(known) < len(ds.Instances) {
+		if len(known) == 0 {
+			return 0
+		}
+		// As in C4.5, the gain among the instances with a value is scaled by the fraction of them
+		return totalWeight(known) / totalWeight(ds.Instances) * InformationGain(ClassifiedDataSet{known}, featureName)
+	}
+
 	// Count number of each feature value and keep track of the current feature's value for each inst
-	featureValueCounts := make(map[Feature]int, len(ds.Instances))
+	featureValueCounts := make(map[Feature]float64, len(ds.Instances))
 	indexToThisFeature := make([]Feature, len(ds.Instances))
 	for i, inst := range ds.Instances {
 		thisFeatureValue := inst.FeatureValues[featureName]
-		featureValueCounts[thisFeatureValue]++
+		featureValueCounts[thisFeatureValue] += inst.weight()
 		indexToThisFeature[i] = thisFeatureValue
 	}
+	total := totalWeight(ds.Instances)
 
-	infoGain := entropy(ds.Instances) // Get entropy
+	infoGain := Entropy(ds.Instances) // Get entropy
 
-	for featureValue, featureCount := range featureValueCounts { // Subtract from entropy to get info gain
+	// Subtract from entropy to get info gain, in order of feature value so rounding is the same every time
+	featureValues := make([]Feature, 0, len(featureValueCounts))
+	for featureValue := range featureValueCounts {
+		featureValues = append(featureValues, featureValue)
+	}
+	sort.Slice(featureValues, func(i, j int) bool { return featureValues[i] < featureValues[j] })
+	for _, featureValue := range featureValues {
+		featureCount := featureValueCounts[featureValue]
 		featureValueInsts := make([]*Instance, 0, len(ds.Instances)) // Instances with featureValue
 		for i, inst := range ds.Instances {
 			if indexToThisFeature[i] == featureValue {
 				featureValueInsts = append(featureValueInsts, inst)
 			}
 		}
-		featureValueEntropy := entropy(featureValueInsts) // Entropy of the instances
-		infoGain -= float64(featureCount) / float64(len(ds.Instances)) * featureValueEntropy
+		featureValueEntropy := Entropy(featureValueInsts) // Entropy of the instances
+		infoGain -= featureCount / total * featureValueEntropy
 	}
 
 	return infoGain
 }
 
-// Calculates entropy of the targetvalues of a slice of instances.
-func entropy(insts []*Instance) float64 {
-	targetCounts := make(map[Target]int, len(insts))
+// A BestFeature function that picks the feature whose split has the lowest weighted Gini impurity, as CART does.
+func BestFeatureGiniImpurity(ds ClassifiedDataSet) string {
+	greatestDecrease := 0.0
+	greatestFeatureName := ""
+	for featureName := range ds.Instances[0].FeatureValues {
+		decrease := impurityDecreaseOfFeature(ds, featureName, giniImpurity)
+		if decrease > greatestDecrease || // Lowest split impurity is the greatest decrease from the node's own
+			decrease == greatestDecrease && greatestFeatureName != "" && featureName < greatestFeatureName { // Ties go to the smallest name
+			greatestDecrease = decrease
+			greatestFeatureName = featureName
+		}
+	}
+	return greatestFeatureName
+}
+
+var _ BestFeatureFunc = BestFeatureGiniImpurity
+
+// A BestFeature function using C4.5's gain ratio: information gain divided by the split information of the
+// feature, which offsets information gain's bias towards features with many values. As in C4.5, only features
+// with at least the average information gain compete, so a tiny split information can't inflate a poor feature.
+// Features with a single value have no split information and are skipped.
+func BestFeatureGainRatio(ds ClassifiedDataSet) string {
+	gains := make(map[string]float64, len(ds.Instances[0].FeatureValues))
+	averageGain := 0.0
+	for featureName := range ds.Instances[0].FeatureValues {
+		gains[featureName] = InformationGain(ds, featureName)
+		averageGain += gains[featureName] / float64(len(ds.Instances[0].FeatureValues))
+	}
+	greatestRatio := 0.0
+	greatestFeatureName := ""
+	for featureName, gain := range gains {
+		splitInfo := ds.SplitInformation(featureName)
+		if gain <= 0 || gain < averageGain || splitInfo == 0 {
+			continue
+		}
+		ratio := gain / splitInfo
+		if ratio > greatestRatio ||
+			ratio == greatestRatio && greatestFeatureName != "" && featureName < greatestFeatureName { // Ties go to the smallest name
+			greatestRatio = ratio
+			greatestFeatureName = featureName
+		}
+	}
+	return greatestFeatureName
+}
+
+var _ BestFeatureFunc = BestFeatureGainRatio
+
+// Determines how much splitting on a feature lowers the impurity of a ClassifiedDataSet, weighting each
+// child's impurity by its share of the instances.
+func impurityDecreaseOfFeature(ds ClassifiedDataSet, featureName string, impurity func([]*Instance) float64) float64 {
+	featureValueToInsts := make(map[Feature][]*Instance)
+	for _, inst := range ds.Instances {
+		featureValueToInsts[inst.FeatureValues[featureName]] = append(featureValueToInsts[inst.FeatureValues[featureName]], inst)
+	}
+	featureValues := make([]Feature, 0, len(featureValueToInsts))
+	for featureValue := range featureValueToInsts {
+		featureValues = append(featureValues, featureValue)
+	}
+	sort.Slice(featureValues, func(i, j int) bool { return featureValues[i] < featureValues[j] })
+
+	decrease, total := impurity(ds.Instances), totalWeight(ds.Instances)
+	for _, featureValue := range featureValues { // In order of feature value so rounding is the same every time
+		insts := featureValueToInsts[featureValue]
+		decrease -= totalWeight(insts) / total * impurity(insts)
+	}
+	return decrease
+}
+
+// Calculates the Gini impurity of the target values of a slice of instances.
+func giniImpurity(insts []*Instance) float64 {
+	targetCounts := make(map[Target]float64, len(insts))
 	for _, inst := range insts {
-		targetCounts[inst.TargetValue]++
+		targetCounts[inst.TargetValue] += inst.weight()
+	}
+	total := totalWeight(insts)
+	targets := make([]Target, 0, len(targetCounts))
+	for target := range targetCounts {
+		targets = append(targets, target)
 	}
+	sort.Slice(targets, func(i, j int) bool { return targetLess(targets[i], targets[j]) })
+	G := 1.0
+	for _, target := range targets { // In order of target, so rounding is the same every time
+		pI := targetCounts[target] / total
+		G -= pI * pI
+	}
+	return G
+}
+
+// Calculates the entropy of a feature's own value distribution, a.k.a. its intrinsic value. Features with many
+// evenly spread values score high, which is why their raw information gain tends to be inflated.
+func (ds ClassifiedDataSet) SplitInformation(featureName string) float64 {
+	featureValueCounts := make(map[Feature]float64)
+	for _, inst := range ds.Instances {
+		featureValueCounts[inst.FeatureValues[featureName]] += inst.weight()
+	}
+	total := totalWeight(ds.Instances)
+	featureValues := make([]Feature, 0, len(featureValueCounts))
+	for featureValue := range featureValueCounts {
+		featureValues = append(featureValues, featureValue)
+	}
+	sort.Slice(featureValues, func(i, j int) bool { return featureValues[i] < featureValues[j] })
 	H := 0.0
-	for _, count := range targetCounts {
-		pI := float64(count) / float64(len(insts))
+	for _, featureValue := range featureValues { // In order of feature value, so rounding is the same every time
+		pI := featureValueCounts[featureValue] / total
 		H += pI * math.Log2(pI)
 	}
 	return -H
 }
+
+// Calculates entropy of the targetvalues of a slice of instances, in bits, counting each instance for its weight.
+// An empty slice or a single target has no entropy.
+func Entropy(insts []*Instance) float64 {
+	return countsEntropy(targetDistribution(insts)) // Sums in order of target, so rounding is the same every time
+}
+
+// Sums the weights of the instances.
+func totalWeight(insts []*Instance) float64 {
+	total := 0.0
+	for _, inst := range insts {
+		total += inst.weight()
+	}
+	return total
+}
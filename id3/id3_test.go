@@ -6,9 +6,10 @@ import (
 	"os"
 	"reflect"
 	"sort"
+	"sync"
 	"testing"
-	"time"
 	"fmt"
+	"math"
 )
 
 func btoFeature(f bool) Feature {
@@ -31,23 +32,27 @@ func TestCandy(t *testing.T) {
 	// Testing candy for "yumminess"
 	var testDataset = ClassifiedDataSet{
 		[]*Instance{
-			{map[string]Feature{"salty": btoFeature(false), "sweet": btoFeature(false)}, btoTarget(false)}, // Bland
-			{map[string]Feature{"salty": btoFeature(true), "sweet": btoFeature(false)}, btoTarget(false)},  // Disgusting
-			{map[string]Feature{"salty": btoFeature(true), "sweet": btoFeature(true)}, btoTarget(true)},    // Savory
-			{map[string]Feature{"salty": btoFeature(false), "sweet": btoFeature(true)}, btoTarget(true)},   // Sugary
+			{map[string]Feature{"salty": btoFeature(false), "sweet": btoFeature(false)}, btoTarget(false), 1}, // Bland
+			{map[string]Feature{"salty": btoFeature(true), "sweet": btoFeature(false)}, btoTarget(false), 1},  // Disgusting
+			{map[string]Feature{"salty": btoFeature(true), "sweet": btoFeature(true)}, btoTarget(true), 1},    // Savory
+			{map[string]Feature{"salty": btoFeature(false), "sweet": btoFeature(true)}, btoTarget(true), 1},   // Sugary
 		},
 	}
 
 	var expectedTree = &Decision{
-		featureName: "sweet",
+		featureName:  "sweet",
+		gain:         1,
+		distribution: map[Target]float64{btoTarget(true): 2, btoTarget(false): 2},
 		nextDecisions: map[Feature]*Decision{
 			btoFeature(true): {
-				isOutput:    true,
-				outputValue: btoTarget(true),
+				isOutput:     true,
+				outputValue:  btoTarget(true),
+				distribution: map[Target]float64{btoTarget(true): 2},
 			},
 			btoFeature(false): {
-				isOutput:    true,
-				outputValue: btoTarget(false),
+				isOutput:     true,
+				outputValue:  btoTarget(false),
+				distribution: map[Target]float64{btoTarget(false): 2},
 			},
 		},
 	}
@@ -75,7 +80,7 @@ func TestCandy(t *testing.T) {
 //12 Overcast Mild High Strong Yes
 //13 Overcast Hot Normal Weak Yes
 //14 Rain Mild High Strong No
-func TestTennis(t *testing.T) {
+func tennisDataSet() ClassifiedDataSet {
 	stof := map[string]Feature{
 		"sunny":    2,
 		"overcast": 1,
@@ -88,43 +93,1585 @@ func TestTennis(t *testing.T) {
 		"strong":   1,
 		"weak":     0,
 	}
-	stot := map[string]Target{
-		"yes": true,
-		"no":  false,
+	stot := map[string]Target{
+		"yes": true,
+		"no":  false,
+	}
+	return ClassifiedDataSet{
+		[]*Instance{
+			{map[string]Feature{"outlook": stof["sunny"], "temp": stof["hot"], "humidity": stof["high"], "wind": stof["weak"]}, stot["no"], 1},
+			{map[string]Feature{"outlook": stof["sunny"], "temp": stof["hot"], "humidity": stof["high"], "wind": stof["strong"]}, stot["no"], 1},
+			{map[string]Feature{"outlook": stof["overcast"], "temp": stof["hot"], "humidity": stof["high"], "wind": stof["weak"]}, stot["yes"], 1},
+			{map[string]Feature{"outlook": stof["rain"], "temp": stof["mild"], "humidity": stof["high"], "wind": stof["weak"]}, stot["yes"], 1},
+			{map[string]Feature{"outlook": stof["rain"], "temp": stof["cool"], "humidity": stof["normal"], "wind": stof["weak"]}, stot["yes"], 1},
+			{map[string]Feature{"outlook": stof["rain"], "temp": stof["cool"], "humidity": stof["normal"], "wind": stof["strong"]}, stot["no"], 1},
+			{map[string]Feature{"outlook": stof["overcast"], "temp": stof["cool"], "humidity": stof["normal"], "wind": stof["strong"]}, stot["yes"], 1},
+			{map[string]Feature{"outlook": stof["sunny"], "temp": stof["mild"], "humidity": stof["high"], "wind": stof["weak"]}, stot["no"], 1},
+			{map[string]Feature{"outlook": stof["sunny"], "temp": stof["cool"], "humidity": stof["normal"], "wind": stof["weak"]}, stot["yes"], 1},
+			{map[string]Feature{"outlook": stof["rain"], "temp": stof["mild"], "humidity": stof["normal"], "wind": stof["weak"]}, stot["yes"], 1},
+			{map[string]Feature{"outlook": stof["sunny"], "temp": stof["mild"], "humidity": stof["normal"], "wind": stof["strong"]}, stot["yes"], 1},
+			{map[string]Feature{"outlook": stof["overcast"], "temp": stof["mild"], "humidity": stof["high"], "wind": stof["strong"]}, stot["yes"], 1},
+			{map[string]Feature{"outlook": stof["overcast"], "temp": stof["hot"], "humidity": stof["normal"], "wind": stof["weak"]}, stot["yes"], 1},
+			{map[string]Feature{"outlook": stof["rain"], "temp": stof["mild"], "humidity": stof["high"], "wind": stof["strong"]}, stot["no"], 1},
+		},
+	}
+}
+
+func TestTennis(t *testing.T) {
+	testDataset := tennisDataSet()
+	dtree, err := Train(testDataset, BestFeatureInformationGain)
+
+	var expectedTree = []string{
+		`outlook[1] ==> true`,
+		`outlook[0] ==> wind[0] ==> true`,
+		`outlook[0] ==> wind[1] ==> false`,
+		`outlook[2] ==> humidity[1] ==> false`,
+		`outlook[2] ==> humidity[0] ==> true`,
+	}
+	sort.Strings(expectedTree)
+	if err != nil {
+		t.Error("Encountered tree training error", err)
+	} else if treeStr := dtree.String(); !reflect.DeepEqual(treeStr, expectedTree) {
+		t.Errorf("Expected %#v got %#v\n", expectedTree, treeStr)
+	}
+}
+
+func TestStringLabeled(t *testing.T) {
+	dtree, err := Train(tennisDataSet(), BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal("Encountered tree training error", err)
+	}
+	featureLabels := map[string]map[Feature]string{
+		"outlook": {0: "rain", 1: "overcast", 2: "sunny"},
+		"wind":    {0: "weak", 1: "strong"},
+	}
+	targetLabels := map[Target]string{true: "play", false: "stay in"}
+	expectedTree := []string{
+		`outlook[overcast] ==> play`,
+		`outlook[rain] ==> wind[strong] ==> stay in`,
+		`outlook[rain] ==> wind[weak] ==> play`,
+		`outlook[sunny] ==> humidity[0] ==> play`,
+		`outlook[sunny] ==> humidity[1] ==> stay in`,
+	}
+	if treeStr := dtree.StringLabeled(featureLabels, targetLabels); !reflect.DeepEqual(treeStr, expectedTree) {
+		t.Errorf("Expected %#v got %#v\n", expectedTree, treeStr)
+	}
+	if !reflect.DeepEqual(dtree.StringLabeled(nil, nil), dtree.String()) {
+		t.Error("Expected no labels to match String, got", dtree.StringLabeled(nil, nil))
+	}
+}
+
+func TestPredictInto(t *testing.T) {
+	dtree, err := Train(tennisDataSet(), BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal("Encountered tree training error", err)
+	}
+	for _, inst := range tennisDataSet().Instances {
+		var result Target = !inst.TargetValue.(bool) // Wrong on purpose, so the prediction must overwrite it
+		if err := dtree.PredictInto(inst.FeatureValues, &result); err != nil {
+			t.Error(err)
+		} else if result != inst.TargetValue {
+			t.Error("Expected", inst.TargetValue, "got", result, "for", inst.FeatureValues)
+		}
+	}
+	var result Target
+	if err := dtree.PredictInto(map[string]Feature{"outlook": 2}, &result); err == nil {
+		t.Error("Expected an error for a missing feature")
+	}
+}
+
+// A dataset of features with several values each, which can't take the binary training path.
+func multiValuedDataSet(n, features int, seed int64) ClassifiedDataSet {
+	rng := rand.New(rand.NewSource(seed))
+	ds := ClassifiedDataSet{}
+	for i := 0; i < n; i++ {
+		inst := &Instance{FeatureValues: make(map[string]Feature, features)}
+		for j := 0; j < features; j++ {
+			inst.FeatureValues[string(rune('a'+j))] = Feature(rng.Intn(4))
+		}
+		inst.TargetValue = Target((inst.FeatureValues["a"]+inst.FeatureValues["b"])%3 == 0) != Target(rng.Intn(10) == 0)
+		ds.Instances = append(ds.Instances, inst)
+	}
+	return ds
+}
+
+func TestParallelTrain(t *testing.T) {
+	ds := multiValuedDataSet(3000, 8, 1)
+	parallel, err := Train(ds, BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sequential, err := trainGeneral(ds, Options{BestFeature: BestFeatureInformationGain})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parallel, sequential) {
+		t.Error("Expected parallel training to build the same tree as sequential training")
+	}
+}
+
+func TestDeterministicTrain(t *testing.T) {
+	// Every feature is a copy of another, so each split is a tie broken by name
+	ds := tennisDataSet()
+	for _, inst := range ds.Instances {
+		inst.FeatureValues["wind copy"] = inst.FeatureValues["wind"]
+		inst.FeatureValues["outlook copy"] = inst.FeatureValues["outlook"]
+		inst.FeatureValues["humidity copy"] = inst.FeatureValues["humidity"]
+	}
+	for _, bf := range []BestFeatureFunc{BestFeatureInformationGain, BestFeatureUncertaintyCoefficient, BestFeatureGiniImpurity, BestFeatureGainRatio} {
+		first, err := trainGeneral(ds, Options{BestFeature: bf})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 20; i++ { // Map iteration order changes from run to run
+			dtree, err := trainGeneral(ds, Options{BestFeature: bf})
+			if err != nil {
+				t.Fatal(err)
+			} else if !reflect.DeepEqual(dtree.String(), first.String()) || !reflect.DeepEqual(dtree, first) {
+				t.Fatal("Expected", first.String(), "got", dtree.String())
+			}
+		}
+		if first.featureName != "outlook" {
+			t.Error("Expected ties to go to the smallest name, got", first.featureName)
+		}
+	}
+}
+
+func BenchmarkTrainParallel(b *testing.B) {
+	ds := multiValuedDataSet(20000, 10, 1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Train(ds, BestFeatureInformationGain)
+	}
+}
+
+func BenchmarkTrainSequential(b *testing.B) {
+	ds := multiValuedDataSet(20000, 10, 1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		trainGeneral(ds, Options{BestFeature: BestFeatureInformationGain})
+	}
+}
+
+func BenchmarkPredictInto(b *testing.B) {
+	dtree, err := Train(tennisDataSet(), BestFeatureInformationGain)
+	if err != nil {
+		b.Fatal(err)
+	}
+	features := tennisDataSet().Instances[0].FeatureValues
+	var result Target
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := dtree.PredictInto(features, &result); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestLeafMeta(t *testing.T) {
+	dtree, err := Train(tennisDataSet(), BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal("Encountered tree training error", err)
+	}
+	actions := map[Target]string{true: "play", false: "stay inside"}
+	dtree.SetLeafMeta("action", func(leaf *Decision) interface{} {
+		return actions[leaf.OutputValue()]
+	})
+
+	for _, inst := range tennisDataSet().Instances {
+		leaf, err := dtree.ClassifyLeaf(inst)
+		if err != nil {
+			t.Error(err)
+		} else if action, ok := leaf.Meta("action"); !ok || action != actions[inst.TargetValue] {
+			t.Error("Expected action", actions[inst.TargetValue], "got", action, "for", inst.FeatureValues)
+		}
+	}
+	if _, ok := dtree.Meta("action"); ok {
+		t.Error("Expected no metadata on the root decision")
+	}
+}
+
+func TestDecisionRegions(t *testing.T) {
+	var testDataset = ClassifiedDataSet{
+		[]*Instance{
+			{map[string]Feature{"x": 0, "y": 0}, false, 1},
+			{map[string]Feature{"x": 0, "y": 1}, false, 1},
+			{map[string]Feature{"x": 1, "y": 0}, false, 1},
+			{map[string]Feature{"x": 1, "y": 1}, true, 1},
+			{map[string]Feature{"x": 2, "y": 0}, true, 1},
+			{map[string]Feature{"x": 2, "y": 1}, true, 1},
+		},
+	}
+	dtree, err := Train(testDataset, BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal("Encountered tree training error", err)
+	}
+
+	regions := dtree.DecisionRegions("x", "y", []Feature{0, 1, 2}, []Feature{0, 1, 2})
+	for _, x := range []Feature{0, 1, 2} {
+		for _, y := range []Feature{0, 1, 2} {
+			inst := &Instance{FeatureValues: map[string]Feature{"x": x, "y": y}}
+			target, ok := regions[[2]Feature{x, y}]
+			if err := dtree.Classify(inst); err != nil {
+				if ok {
+					t.Error("Expected no region for unclassifiable point", x, y)
+				}
+			} else if !ok || target != inst.TargetValue {
+				t.Error("Expected region", x, y, "to be", inst.TargetValue, "got", target)
+			}
+		}
+	}
+	if _, ok := regions[[2]Feature{1, 2}]; ok || len(regions) != 8 { // Only x=1 consults y
+		t.Error("Expected every point but (1, 2) to be classifiable, got", regions)
+	}
+
+	// A missing x is voted on across its branches, as Predict does
+	missing := &Instance{FeatureValues: map[string]Feature{"x": FeatureMissing, "y": 1}}
+	expected, err := dtree.Predict(missing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target, ok := dtree.DecisionRegions("x", "y", []Feature{FeatureMissing}, []Feature{1})[[2]Feature{FeatureMissing, 1}]; !ok || target != expected {
+		t.Error("Expected a missing x to be", expected, "got", target)
+	}
+}
+
+func TestReachableLeaves(t *testing.T) {
+	dtree, err := Train(tennisDataSet(), BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal("Encountered tree training error", err)
+	}
+
+	// Rain splits on wind: weak plays, strong doesn't
+	rain := dtree.nextDecisions[0]
+	leaves := dtree.ReachableLeaves(map[string]Feature{"outlook": 0})
+	if !reflect.DeepEqual(leaves, []*Decision{rain.nextDecisions[0], rain.nextDecisions[1]}) {
+		t.Error("Expected the two wind leaves under rain, got", leaves)
+	}
+	if leaves := dtree.ReachableLeaves(map[string]Feature{"outlook": 0, "wind": 1}); len(leaves) != 1 || leaves[0].outputValue != Target(false) {
+		t.Error("Expected the single rainy, windy leaf, got", leaves)
+	}
+	if leaves := dtree.ReachableLeaves(nil); len(leaves) != 5 {
+		t.Error("Expected all 5 leaves, got", leaves)
+	}
+	if leaves := dtree.ReachableLeaves(map[string]Feature{"outlook": 7}); len(leaves) != 0 {
+		t.Error("Expected no leaves for an unseen value, got", leaves)
+	}
+}
+
+func TestClassifyExplain(t *testing.T) {
+	dtree, err := Train(tennisDataSet(), BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal("Encountered tree training error", err)
+	}
+
+	sunny := tennisDataSet().Instances[0] // Sunny, hot, high humidity and weak wind: don't play
+	if target, featureName, err := dtree.ClassifyExplain(sunny); err != nil {
+		t.Error(err)
+	} else if target != Target(false) || featureName != "humidity" {
+		t.Error("Expected false explained by humidity, got", target, featureName)
+	}
+	overcast := tennisDataSet().Instances[2]
+	if _, featureName, err := dtree.ClassifyExplain(overcast); err != nil {
+		t.Error(err)
+	} else if featureName != "outlook" {
+		t.Error("Expected an overcast prediction explained by outlook, got", featureName)
+	}
+	if _, _, err := dtree.ClassifyExplain(&Instance{FeatureValues: map[string]Feature{}}); err == nil {
+		t.Error("Expected an error for a missing feature")
+	}
+	if _, _, err := dtree.ClassifyExplain(&Instance{FeatureValues: map[string]Feature{"outlook": FeatureMissing}}); err == nil {
+		t.Error("Expected an error explaining an instance missing a split's value")
+	}
+}
+
+func TestClassifyWith(t *testing.T) {
+	// The first two instances contradict each other, leaving a 50/50 leaf
+	var testDataset = ClassifiedDataSet{
+		[]*Instance{
+			{map[string]Feature{"a": 0}, true, 1},
+			{map[string]Feature{"a": 0}, false, 1},
+			{map[string]Feature{"a": 1}, true, 1},
+		},
+	}
+	dtree, err := Train(testDataset, BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal("Encountered tree training error", err)
+	}
+
+	tied := &Instance{FeatureValues: map[string]Feature{"a": 0}}
+	if probs, err := dtree.ClassifyProbabilities(tied); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(probs, map[Target]float64{true: 0.5, false: 0.5}) {
+		t.Error("Expected a 50/50 leaf, got", probs)
+	}
+	var gotTied []Target
+	preferTrue := func(tied []Target) Target {
+		gotTied = tied
+		return true
+	}
+	if target, err := dtree.ClassifyWith(tied, preferTrue); err != nil {
+		t.Error(err)
+	} else if target != Target(true) || !reflect.DeepEqual(gotTied, []Target{false, true}) {
+		t.Error("Expected the callback to pick true from [false true], got", target, "from", gotTied)
+	}
+	if target, err := dtree.ClassifyWith(tied, nil); err != nil {
+		t.Error(err)
+	} else if target != Target(false) {
+		t.Error("Expected the smallest target by default, got", target)
+	}
+
+	gotTied = nil
+	if target, err := dtree.ClassifyWith(testDataset.Instances[2], preferTrue); err != nil {
+		t.Error(err)
+	} else if target != Target(true) || gotTied != nil {
+		t.Error("Expected no tie to break for a pure leaf, got", target, gotTied)
+	}
+}
+
+func TestBestFeatureUncertaintyCoefficient(t *testing.T) {
+	if featureName := BestFeatureUncertaintyCoefficient(tennisDataSet()); featureName != "outlook" {
+		t.Error("Expected outlook to be picked first, as with information gain, got", featureName)
+	}
+
+	pure := ClassifiedDataSet{
+		[]*Instance{
+			{map[string]Feature{"a": 0}, true, 1},
+			{map[string]Feature{"a": 1}, true, 1},
+		},
+	}
+	if featureName := BestFeatureUncertaintyCoefficient(pure); featureName != "" {
+		t.Error("Expected no feature for a pure node, got", featureName)
+	}
+
+	dtree, err := Train(tennisDataSet(), BestFeatureUncertaintyCoefficient)
+	if err != nil {
+		t.Fatal("Encountered tree training error", err)
+	}
+	expectedTree, _ := Train(tennisDataSet(), BestFeatureInformationGain)
+	if !reflect.DeepEqual(dtree.String(), expectedTree.String()) {
+		t.Error("Expected the same tree as information gain, got", dtree.String())
+	}
+}
+
+func TestAverageDepth(t *testing.T) {
+	dtree, err := Train(tennisDataSet(), BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal("Encountered tree training error", err)
+	}
+	// The 4 overcast days take one decision, the other 10 take two
+	if depth, err := dtree.AverageDepth(tennisDataSet()); err != nil {
+		t.Error(err)
+	} else if math.Abs(depth-24.0/14) > 1e-9 {
+		t.Error("Expected an average depth of 24/14, got", depth)
+	}
+	if _, err := dtree.AverageDepth(ClassifiedDataSet{}); err == nil {
+		t.Error("Expected an error for an empty dataset")
+	}
+	if _, err := dtree.AverageDepth(ClassifiedDataSet{[]*Instance{{FeatureValues: map[string]Feature{"outlook": 7}}}}); err == nil {
+		t.Error("Expected an error for an unclassifiable instance")
+	}
+}
+
+func TestMinSamplesSplit(t *testing.T) {
+	// Every child of the root has fewer than 6 instances
+	dtree, err := TrainWithOptions(tennisDataSet(), Options{MinSamplesSplit: 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stump, err := TrainWithOptions(tennisDataSet(), Options{MaxDepth: 1})
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(dtree, stump) {
+		t.Error("Expected", stump.String(), "got", dtree.String())
+	}
+
+	ds := multiValuedDataSet(500, 6, 1)
+	dtree, err = TrainWithOptions(ds, Options{MinSamplesSplit: 40})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, node := range dtree.decisionNodes() {
+		if support := node.Stats().Support; support < 40 {
+			t.Error("Expected every split to have at least 40 instances, got", support)
+		}
+	}
+	if full, _ := Train(ds, BestFeatureInformationGain); dtree.NodeCount() >= full.NodeCount() {
+		t.Error("Expected fewer than", full.NodeCount(), "nodes, got", dtree.NodeCount())
+	}
+}
+
+func TestLimitedTrain(t *testing.T) {
+	full, err := Train(tennisDataSet(), BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for maxDepth, expectedDepth := range []int{0, 1, 2, 2} {
+		dtree, err := LimitedTrain(tennisDataSet(), BestFeatureInformationGain, maxDepth)
+		if err != nil {
+			t.Fatal(err)
+		} else if dtree.Depth() != expectedDepth {
+			t.Error("Expected depth", expectedDepth, "with a bound of", maxDepth, "got", dtree.String())
+		} else if maxDepth >= 2 && !dtree.Equal(full) {
+			t.Error("Expected the full tree with a bound of", maxDepth, "got", dtree.String())
+		}
+	}
+	stump, err := LimitedTrain(tennisDataSet(), BestFeatureGainRatio, 1)
+	if err != nil {
+		t.Fatal(err)
+	} else if stump.Depth() != 1 || stump.featureName != "outlook" {
+		t.Error("Expected a stump on outlook, got", stump.String())
+	}
+	if leaf, err := LimitedTrain(tennisDataSet(), BestFeatureInformationGain, -1); err != nil || !leaf.isOutput || leaf.outputValue != Target(true) {
+		t.Error("Expected a lone leaf predicting true, got", leaf, err)
+	}
+	if _, err := LimitedTrain(ClassifiedDataSet{}, BestFeatureInformationGain, 0); err == nil {
+		t.Error("Expected an error training on no instances")
+	}
+}
+
+func TestContradictoryInstances(t *testing.T) {
+	ds := ClassifiedDataSet{
+		[]*Instance{
+			{map[string]Feature{"a": 1, "b": 0}, true, 1},
+			{map[string]Feature{"a": 1, "b": 0}, false, 1},
+			{map[string]Feature{"a": 1, "b": 0}, true, 1},
+		},
+	}
+	// Splits whenever there is a feature left, even one with a single value
+	alwaysSplit := func(ds ClassifiedDataSet) string {
+		for _, featureName := range []string{"a", "b"} {
+			if _, ok := ds.Instances[0].FeatureValues[featureName]; ok {
+				return featureName
+			}
+		}
+		return ""
+	}
+	for _, bf := range []BestFeatureFunc{BestFeatureInformationGain, BestFeatureGainRatio, alwaysSplit} {
+		dtree, err := trainGeneral(ds, Options{BestFeature: bf})
+		if err != nil {
+			t.Error(err)
+		} else if !dtree.isOutput || dtree.outputValue != Target(true) {
+			t.Error("Expected a majority leaf predicting true, got", dtree.String())
+		}
+	}
+
+	// Contradictions below the root only stop their own branch
+	ds.Instances = append(ds.Instances, &Instance{map[string]Feature{"a": 0, "b": 1}, false, 1})
+	dtree, err := TrainWithOptions(ds, Options{BestFeature: alwaysSplit})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedTree := []string{`a[0] ==> false`, `a[1] ==> true`}
+	if treeStr := dtree.String(); !reflect.DeepEqual(treeStr, expectedTree) {
+		t.Error("Expected", expectedTree, "got", treeStr)
+	}
+}
+
+func TestOneR(t *testing.T) {
+	// Outlook and humidity each get 4 of the 14 wrong, temperature and wind 5
+	ds := tennisDataSet()
+	if featureName := BestFeatureOneR(ds); featureName != "humidity" {
+		t.Error("Expected humidity, got", featureName)
+	}
+	dtree, err := TrainOneR(ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedTree := []string{`humidity[0] ==> true`, `humidity[1] ==> false`}
+	if treeStr := dtree.String(); !reflect.DeepEqual(treeStr, expectedTree) {
+		t.Error("Expected", expectedTree, "got", treeStr)
+	} else if trainError, _ := dtree.CalculateError(ds); trainError != 4.0/14 {
+		t.Error("Expected 4 of 14 wrong, got", trainError)
+	}
+
+	pure := ClassifiedDataSet{ds.Instances[2:4]}
+	if featureName := BestFeatureOneR(pure); featureName != "" {
+		t.Error("Expected no feature for a pure node, got", featureName)
+	}
+	// Every feature of the majority dataset gets 2 of 8 wrong, against 4 for the majority alone
+	if featureName := BestFeatureOneR(majorityDataSet()); featureName != "a" {
+		t.Error("Expected a, got", featureName)
+	}
+}
+
+func TestMostPopularTargetTies(t *testing.T) {
+	insts := []*Instance{
+		{map[string]Feature{"a": 0}, "spam", 1},
+		{map[string]Feature{"a": 0}, "ham", 1},
+		{map[string]Feature{"a": 1}, "eggs", 0.5},
+		{map[string]Feature{"a": 1}, "ham", 1},
+		{map[string]Feature{"a": 1}, "spam", 1},
+	}
+	for i := 0; i < 10; i++ {
+		shuffled := shuffle(insts, rand.New(rand.NewSource(int64(i))))
+		if target := mostPopularTarget(shuffled); target != "ham" {
+			t.Fatal("Expected the tie to go to ham, got", target)
+		}
+	}
+
+	// An exact 50/50 split leaves a single majority leaf, predicting false in whatever order the instances come
+	half := ClassifiedDataSet{}
+	for i := 0; i < 10; i++ {
+		half.Instances = append(half.Instances, &Instance{map[string]Feature{"a": 0}, Target(i%2 == 0), 1})
+	}
+	for i := 0; i < 10; i++ {
+		dtree, err := Train(ClassifiedDataSet{shuffle(half.Instances, rand.New(rand.NewSource(int64(i))))}, BestFeatureInformationGain)
+		if err != nil {
+			t.Fatal(err)
+		} else if !dtree.isOutput || dtree.outputValue != Target(false) {
+			t.Fatal("Expected a leaf predicting false, got", dtree.String())
+		}
+	}
+}
+
+func TestReducedErrorPrune(t *testing.T) {
+	ds := randomBinaryDataSet(600, 8, 1)
+	train, validate := ClassifiedDataSet{ds.Instances[:400]}, ClassifiedDataSet{ds.Instances[400:]}
+	dtree, err := Train(train, BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unpruned, nodes := dtree.Clone(), dtree.NodeCount()
+	stats, err := dtree.ReducedErrorPrune(validate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before, _ := unpruned.CalculateError(validate); stats.ErrorBefore != before {
+		t.Error("Expected an error before of", before, "got", stats.ErrorBefore)
+	}
+	if after, _ := dtree.CalculateError(validate); stats.ErrorAfter != after || after > stats.ErrorBefore {
+		t.Error("Expected an error after of", after, "no worse than", stats.ErrorBefore, "got", stats.ErrorAfter)
+	}
+	if stats.CollapsedNodes == 0 || dtree.NodeCount() >= nodes {
+		t.Error("Expected collapsed nodes to shrink the tree from", nodes, "nodes, got", stats.CollapsedNodes, dtree.NodeCount())
+	}
+
+	// A perfect tree has nothing worth collapsing
+	tennis, err := Train(tennisDataSet(), BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats, err := tennis.ReducedErrorPrune(tennisDataSet()); err != nil || stats != (PruneStats{0, 0, 0}) {
+		t.Error("Expected no collapses and no error, got", stats, err)
+	}
+}
+
+func TestReducedErrorPruneBaseline(t *testing.T) {
+	split := func(featureName string) *Decision {
+		return &Decision{featureName: featureName, nextDecisions: map[Feature]*Decision{
+			0: {isOutput: true, outputValue: true},
+			1: {isOutput: true, outputValue: false},
+		}}
+	}
+	dtree := &Decision{featureName: "a", nextDecisions: map[Feature]*Decision{0: split("b"), 1: split("c")}}
+	// Collapsing the split on b fixes 3 instances, while collapsing the one on c would then break one. Against the
+	// error before any collapse, both would look like improvements.
+	validate := ClassifiedDataSet{
+		[]*Instance{
+			{map[string]Feature{"a": 0, "b": 0, "c": 0}, true, 1},
+			{map[string]Feature{"a": 0, "b": 1, "c": 0}, true, 1},
+			{map[string]Feature{"a": 0, "b": 1, "c": 0}, true, 1},
+			{map[string]Feature{"a": 0, "b": 1, "c": 0}, true, 1},
+			{map[string]Feature{"a": 1, "b": 0, "c": 0}, true, 1},
+			{map[string]Feature{"a": 1, "b": 0, "c": 1}, false, 1},
+			{map[string]Feature{"a": 1, "b": 0, "c": 1}, false, 1},
+		},
+	}
+	stats, err := dtree.ReducedErrorPrune(validate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (PruneStats{1, 3.0 / 7, 0}); stats != expected {
+		t.Error("Expected", expected, "got", stats)
+	}
+	expectedTree := []string{`a[0] ==> true`, `a[1] ==> c[0] ==> true`, `a[1] ==> c[1] ==> false`}
+	if treeStr := dtree.String(); !reflect.DeepEqual(treeStr, expectedTree) {
+		t.Error("Expected", expectedTree, "got", treeStr)
+	}
+}
+
+func TestReducedErrorPruneDeterministic(t *testing.T) {
+	ds := randomBinaryDataSet(600, 8, 8)
+	train, validate := ClassifiedDataSet{ds.Instances[:400]}, ClassifiedDataSet{ds.Instances[400:]}
+	dtree, err := Train(train, BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Instances missing a value are voted on by sibling subtrees, so collapsing one changes whether collapsing
+	// another helps, and the outcome would depend on the order they were tried in
+	for i, inst := range validate.Instances {
+		if i%3 == 0 {
+			inst.FeatureValues["c"] = FeatureMissing
+		}
+	}
+	var expected []string
+	for i := 0; i < 20; i++ {
+		pruned := dtree.Clone()
+		if _, err := pruned.ReducedErrorPrune(validate); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			expected = pruned.String()
+		} else if treeStr := pruned.String(); !reflect.DeepEqual(treeStr, expected) {
+			t.Fatal("Expected every run to prune the same way, got", treeStr, "after", expected)
+		}
+	}
+}
+
+func TestReducedErrorPruneMissingValues(t *testing.T) {
+	split := func() *Decision {
+		return &Decision{featureName: "b", nextDecisions: map[Feature]*Decision{
+			0: {isOutput: true, outputValue: true},
+			1: {isOutput: true, outputValue: false},
+		}}
+	}
+	dtree := &Decision{featureName: "a", nextDecisions: map[Feature]*Decision{0: split(), 1: split()}}
+	// Instances missing a are voted on by both splits on b, so they should judge collapsing either
+	validate := ClassifiedDataSet{
+		[]*Instance{
+			{map[string]Feature{"a": FeatureMissing, "b": 0}, false, 1},
+			{map[string]Feature{"a": FeatureMissing, "b": 0}, false, 1},
+		},
+	}
+	stats, err := dtree.ReducedErrorPrune(validate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (PruneStats{2, 1, 0}); stats != expected {
+		t.Error("Expected", expected, "got", stats)
+	}
+}
+
+func TestReducedErrorPruneUnreachedBranches(t *testing.T) {
+	dtree, err := Train(tennisDataSet(), BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// No validation instance reaches the overcast or sunny branches, so collapsing them can't change the error
+	validate := ClassifiedDataSet{}
+	for _, inst := range tennisDataSet().Instances {
+		if inst.FeatureValues["outlook"] == 0 {
+			validate.Instances = append(validate.Instances, inst)
+		}
+	}
+	if _, err := dtree.ReducedErrorPrune(validate); err != nil {
+		t.Fatal(err)
+	}
+	sunny := &Instance{map[string]Feature{"outlook": 2, "temp": 0, "humidity": 0, "wind": 0}, nil, 1}
+	if target, err := dtree.Predict(sunny); err != nil || target != Target(false) {
+		t.Error("Expected the sunny branch to predict its training majority of false, got", target, err)
+	}
+	if probs, err := dtree.ClassifyProbabilities(sunny); err != nil || probs[false] != 0.6 || probs[true] != 0.4 {
+		t.Error("Expected the collapsed leaf to keep its training distribution, got", probs, err)
+	}
+	overcast := &Instance{map[string]Feature{"outlook": 1, "temp": 0, "humidity": 0, "wind": 0}, nil, 1}
+	if target, err := dtree.Predict(overcast); err != nil || target != Target(true) {
+		t.Error("Expected the overcast branch to predict true, got", target, err)
+	}
+}
+
+func TestEmptyEvaluation(t *testing.T) {
+	dtree, err := Train(tennisDataSet(), BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calculatedError, err := dtree.CalculateError(ClassifiedDataSet{}); err == nil || math.IsNaN(calculatedError) {
+		t.Error("Expected an error rather than NaN for no instances, got", calculatedError, err)
+	}
+	if _, err := dtree.ReducedErrorPrune(ClassifiedDataSet{}); err == nil {
+		t.Error("Expected an error pruning without validation instances")
+	} else if treeStr := dtree.String(); len(treeStr) != 5 {
+		t.Error("Expected the tree to be left unpruned, got", treeStr)
+	}
+}
+
+func TestUnknownBestFeature(t *testing.T) {
+	misspelled := func(ds ClassifiedDataSet) string { return "outlok" }
+	if _, err := Train(tennisDataSet(), misspelled); err == nil || err.Error() != "best feature function picked outlok, which no instance has" {
+		t.Error("Expected an error naming the unknown feature, got", err)
+	}
+
+	// Picking outlook again below the root, after it has been split on
+	alwaysOutlook := func(ds ClassifiedDataSet) string { return "outlook" }
+	if _, err := Train(tennisDataSet(), alwaysOutlook); err == nil {
+		t.Error("Expected an error picking a feature already split on")
+	}
+	if _, err := TrainWithOptions(tennisDataSet(), Options{BestFeature: alwaysOutlook, ContinuousFeatures: map[string]bool{"temp": true}}); err == nil {
+		t.Error("Expected an error alongside continuous features too")
+	}
+}
+
+func TestInconsistentFeatures(t *testing.T) {
+	ds := tennisDataSet()
+	delete(ds.Instances[3].FeatureValues, "wind")
+	delete(ds.Instances[3].FeatureValues, "humidity")
+	delete(ds.Instances[5].FeatureValues, "temp")
+	_, err := Train(ds, BestFeatureInformationGain)
+	if err == nil || err.Error() != "instance 3 is missing features [humidity wind]" {
+		t.Error("Expected an error naming instance 3 and its missing features, got", err)
+	}
+	if _, err := LimitedTrain(ds, BestFeatureInformationGain, 3); err == nil {
+		t.Error("Expected LimitedTrain to check features too")
+	}
+	if _, err := TrainWithOptions(ds, Options{AllowInconsistentFeatures: true}); err != nil {
+		t.Error("Expected inconsistent features to be allowed, got", err)
+	}
+}
+
+func TestStats(t *testing.T) {
+	ds := tennisDataSet()
+	dtree, err := TrainWithOptions(ds, Options{MaxDepth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reaching := make(map[*Decision]float64)
+	for _, inst := range ds.Instances {
+		leaf, err := dtree.ClassifyLeaf(inst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reaching[leaf]++
+	}
+	for leaf, count := range reaching {
+		if support := leaf.Stats().Support; support != count {
+			t.Error("Expected support", count, "got", support)
+		}
+	}
+	if support := dtree.Stats().Support; support != 14 {
+		t.Error("Expected the root to be supported by all 14 instances, got", support)
+	}
+
+	sunny := dtree.nextDecisions[2].Stats()
+	expectedDistribution := map[Target]float64{true: 2, false: 3}
+	if !reflect.DeepEqual(sunny.Distribution, expectedDistribution) {
+		t.Error("Expected", expectedDistribution, "got", sunny.Distribution)
+	} else if sunny.RunnerUp != Target(true) || sunny.RunnerUpSupport != 2 {
+		t.Error("Expected a runner-up of true with support 2, got", sunny.RunnerUp, sunny.RunnerUpSupport)
+	}
+	if overcast := dtree.nextDecisions[1].Stats(); overcast.RunnerUpSupport != 0 {
+		t.Error("Expected no runner-up for a pure leaf, got", overcast.RunnerUp, overcast.RunnerUpSupport)
+	}
+}
+
+func TestMajorityTarget(t *testing.T) {
+	dtree, err := Train(tennisDataSet(), BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Rain is 3 to 2 for true and sunny 3 to 2 for false, while the root has 9 of 14 true
+	expected := map[*Decision]Target{
+		dtree:                                   true,
+		dtree.nextDecisions[0]:                  true,
+		dtree.nextDecisions[1]:                  true,
+		dtree.nextDecisions[2]:                  false,
+		dtree.nextDecisions[2].nextDecisions[0]: true,
+	}
+	for node, target := range expected {
+		if majority := node.MajorityTarget(); majority != target {
+			t.Error("Expected", target, "for", node.featureName, "got", majority)
+		}
+	}
+
+	tied := &Decision{featureName: "a", distribution: map[Target]float64{"y": 2, "x": 2}}
+	if majority := tied.MajorityTarget(); majority != "x" {
+		t.Error("Expected a tie to go to x, got", majority)
+	}
+	if majority := (&Decision{featureName: "a"}).MajorityTarget(); majority != nil {
+		t.Error("Expected no majority without training counts, got", majority)
+	}
+}
+
+func TestIsConsistent(t *testing.T) {
+	ds := tennisDataSet()
+	dtree, err := Train(ds, BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if consistent, err := dtree.IsConsistent(ds); err != nil {
+		t.Error(err)
+	} else if !consistent {
+		t.Error("Expected a fully grown tree to be consistent with its noise-free training set")
+	}
+
+	stump, err := TrainWithOptions(ds, Options{MaxDepth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if consistent, err := stump.IsConsistent(ds); err != nil {
+		t.Error(err)
+	} else if consistent {
+		t.Error("Expected a stump to misclassify some of the training set")
+	}
+	if !reflect.DeepEqual(ds, tennisDataSet()) {
+		t.Error("Expected the instances to be left unmodified")
+	}
+
+	// Missing values are voted on, as CalculateError does, rather than erroring
+	missing := ClassifiedDataSet{[]*Instance{{map[string]Feature{"outlook": FeatureMissing, "humidity": 1, "wind": 1}, false, 1}}}
+	calculatedError, err := dtree.CalculateError(missing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if consistent, err := dtree.IsConsistent(missing); err != nil || consistent != (calculatedError == 0) {
+		t.Error("Expected consistency to agree with an error of", calculatedError, "got", consistent, err)
+	}
+}
+
+func TestMaxChildren(t *testing.T) {
+	ds := ClassifiedDataSet{}
+	for size, count := range []int{6, 5, 4, 3, 2, 1} {
+		for i := 0; i < count; i++ {
+			ds.Instances = append(ds.Instances, &Instance{map[string]Feature{"size": Feature(size)}, Target(size%2 == 0), 1})
+		}
+	}
+	dtree, err := TrainWithOptions(ds, Options{MaxChildren: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	children := make(map[*Decision]bool)
+	for _, child := range dtree.nextDecisions {
+		children[child] = true
 	}
-	var testDataset = ClassifiedDataSet{
+	if len(children) > 3 {
+		t.Error("Expected at most 3 children, got", len(children))
+	}
+	if len(dtree.nextDecisions) != 6 || dtree.nextDecisions[2] != dtree.nextDecisions[5] {
+		t.Error("Expected every value to route to a child, with the least populated ones merged, got", dtree.nextDecisions)
+	}
+	for _, inst := range ds.Instances {
+		if _, err := dtree.ClassifyLeaf(inst); err != nil {
+			t.Error(err)
+		}
+	}
+
+	if dtree, err = TrainWithOptions(ds, Options{}); err != nil {
+		t.Fatal(err)
+	} else if len(dtree.nextDecisions) != 6 {
+		t.Error("Expected a child per value without the limit, got", len(dtree.nextDecisions))
+	}
+}
+
+func TestWeightedInstances(t *testing.T) {
+	// Three negatives outvote the positive among the sweet instances, unless it weighs more than they do together
+	ds := ClassifiedDataSet{
 		[]*Instance{
-			{map[string]Feature{"outlook": stof["sunny"], "temp": stof["hot"], "humidity": stof["high"], "wind": stof["weak"]}, stot["no"]},
-			{map[string]Feature{"outlook": stof["sunny"], "temp": stof["hot"], "humidity": stof["high"], "wind": stof["strong"]}, stot["no"]},
-			{map[string]Feature{"outlook": stof["overcast"], "temp": stof["hot"], "humidity": stof["high"], "wind": stof["weak"]}, stot["yes"]},
-			{map[string]Feature{"outlook": stof["rain"], "temp": stof["mild"], "humidity": stof["high"], "wind": stof["weak"]}, stot["yes"]},
-			{map[string]Feature{"outlook": stof["rain"], "temp": stof["cool"], "humidity": stof["normal"], "wind": stof["weak"]}, stot["yes"]},
-			{map[string]Feature{"outlook": stof["rain"], "temp": stof["cool"], "humidity": stof["normal"], "wind": stof["strong"]}, stot["no"]},
-			{map[string]Feature{"outlook": stof["overcast"], "temp": stof["cool"], "humidity": stof["normal"], "wind": stof["strong"]}, stot["yes"]},
-			{map[string]Feature{"outlook": stof["sunny"], "temp": stof["mild"], "humidity": stof["high"], "wind": stof["weak"]}, stot["no"]},
-			{map[string]Feature{"outlook": stof["sunny"], "temp": stof["cool"], "humidity": stof["normal"], "wind": stof["weak"]}, stot["yes"]},
-			{map[string]Feature{"outlook": stof["rain"], "temp": stof["mild"], "humidity": stof["normal"], "wind": stof["weak"]}, stot["yes"]},
-			{map[string]Feature{"outlook": stof["sunny"], "temp": stof["mild"], "humidity": stof["normal"], "wind": stof["strong"]}, stot["yes"]},
-			{map[string]Feature{"outlook": stof["overcast"], "temp": stof["mild"], "humidity": stof["high"], "wind": stof["strong"]}, stot["yes"]},
-			{map[string]Feature{"outlook": stof["overcast"], "temp": stof["hot"], "humidity": stof["normal"], "wind": stof["weak"]}, stot["yes"]},
-			{map[string]Feature{"outlook": stof["rain"], "temp": stof["mild"], "humidity": stof["high"], "wind": stof["strong"]}, stot["no"]},
+			{map[string]Feature{"salty": 0, "sweet": 1}, false, 0},
+			{map[string]Feature{"salty": 0, "sweet": 1}, false, 0},
+			{map[string]Feature{"salty": 0, "sweet": 1}, false, 0},
+			{map[string]Feature{"salty": 0, "sweet": 1}, true, 0},
+			{map[string]Feature{"salty": 1, "sweet": 0}, false, 0},
 		},
 	}
-	dtree, err := Train(testDataset, BestFeatureInformationGain)
+	if target := mostPopularTarget(ds.Instances); target != Target(false) {
+		t.Error("Expected unweighted instances to count once each, got", target)
+	}
+	ds.Instances[3].Weight = 4
+	if target := mostPopularTarget(ds.Instances[:4]); target != Target(true) {
+		t.Error("Expected the weighted positive to win, got", target)
+	} else if target := mostPopularTarget(ds.Instances); target != Target(false) {
+		t.Error("Expected the even weighted split to go to false, got", target)
+	}
+	if H := Entropy(ds.Instances); math.Abs(H-1) > 1e-9 {
+		t.Error("Expected an even weighted split to have entropy 1, got", H)
+	}
+	dtree, err := Train(ds, BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leaf, err := dtree.leafFor(map[string]Feature{"salty": 0, "sweet": 1}); err != nil {
+		t.Fatal(err)
+	} else if leaf.outputValue != Target(true) || leaf.distribution[Target(true)] != 4 {
+		t.Error("Expected a leaf predicting true with weight 4 behind it, got", leaf.outputValue, leaf.distribution)
+	}
+	// The three negatives it gets wrong weigh 3 of 8
+	if calculatedError, err := dtree.CalculateError(ds); err != nil {
+		t.Fatal(err)
+	} else if calculatedError != 3.0/8 {
+		t.Error("Expected", 3.0/8, "got", calculatedError)
+	}
 
-	var expectedTree = []string{
-		`outlook[1] ==> true`,
-		`outlook[0] ==> wind[0] ==> true`,
-		`outlook[0] ==> wind[1] ==> false`,
-		`outlook[2] ==> humidity[1] ==> false`,
-		`outlook[2] ==> humidity[0] ==> true`,
+	// Duplicating an instance and doubling its weight train the same tree
+	duplicated, weighted := tennisDataSet(), tennisDataSet()
+	duplicated.Instances = append(duplicated.Instances, duplicated.Instances[0].Clone())
+	weighted.Instances[0].Weight = 2
+	a, err := Train(duplicated, BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
 	}
-	sort.Strings(expectedTree)
+	b, err := Train(weighted, BestFeatureInformationGain)
 	if err != nil {
-		t.Error("Encountered tree training error", err)
-	} else if treeStr := dtree.String(); !reflect.DeepEqual(treeStr, expectedTree) {
+		t.Fatal(err)
+	} else if !sameTree(a, b) {
+		t.Error("Expected", a.String(), "got", b.String())
+	}
+}
+
+func TestMissingFeatureValues(t *testing.T) {
+	ds := tennisDataSet()
+	gain := InformationGain(ds, "outlook")
+	// Forgetting the outlook of an overcast day leaves 13 of 14 known
+	ds.Instances[2].FeatureValues["outlook"] = FeatureMissing
+	known := ClassifiedDataSet{}
+	for _, inst := range ds.Instances {
+		if inst.FeatureValues["outlook"] != FeatureMissing {
+			known.Instances = append(known.Instances, inst)
+		}
+	}
+	if missingGain, expected := InformationGain(ds, "outlook"), 13.0/14*InformationGain(known, "outlook"); math.Abs(missingGain-expected) > 1e-9 {
+		t.Error("Expected", expected, "got", missingGain)
+	} else if missingGain >= gain {
+		t.Error("Expected missing values to lower outlook's gain of", gain, "got", missingGain)
+	}
+
+	dtree, err := Train(ds, BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The missing day joins rain, overcast and sunny in proportion to their 5, 3 and 5 known days
+	if support := dtree.nextDecisions[1].Stats().Support; math.Abs(support-(3+3.0/13)) > 1e-9 {
+		t.Error("Expected overcast to be supported by", 3+3.0/13, "got", support)
+	}
+
+	// Rain and sunny days are usually played with normal humidity and a weak wind, and overcast days always
+	for _, test := range []struct {
+		humidity, wind Feature
+		expected       Target
+	}{{0, 0, true}, {1, 1, false}} {
+		inst := &Instance{map[string]Feature{"outlook": FeatureMissing, "temp": 1, "humidity": test.humidity, "wind": test.wind}, nil, 0}
+		if target, err := dtree.Predict(inst); err != nil {
+			t.Error(err)
+		} else if target != test.expected {
+			t.Error("Expected", test.expected, "for", inst.FeatureValues, "got", target)
+		}
+	}
+	inst := &Instance{map[string]Feature{"outlook": FeatureMissing, "temp": 1, "humidity": 1, "wind": 1}, nil, 0}
+	if probs, err := dtree.ClassifyProbabilities(inst); err != nil {
+		t.Error(err)
+	} else if math.Abs(probs[Target(true)]+probs[Target(false)]-1) > 1e-9 || probs[Target(true)] <= 0 {
+		t.Error("Expected overcast's share of the vote for true, got", probs)
+	}
+	if _, err := dtree.ClassifyLeaf(inst); err == nil {
+		t.Error("Expected no single leaf for a missing value")
+	}
+}
+
+func TestConfusionMatrix(t *testing.T) {
+	ds := ClassifiedDataSet{
+		[]*Instance{
+			{map[string]Feature{"salty": 0, "sweet": 0}, false, 1},
+			{map[string]Feature{"salty": 1, "sweet": 0}, false, 1},
+			{map[string]Feature{"salty": 1, "sweet": 0}, false, 1},
+			{map[string]Feature{"salty": 1, "sweet": 1}, true, 1},
+			{map[string]Feature{"salty": 0, "sweet": 1}, true, 1},
+		},
+	}
+	// Splits on the wrong feature
+	dtree := &Decision{featureName: "salty", nextDecisions: map[Feature]*Decision{
+		0: {isOutput: true, outputValue: false},
+		1: {isOutput: true, outputValue: true},
+	}}
+	matrix, err := dtree.ConfusionMatrix(ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[Target]map[Target]float64{
+		false: {false: 1, true: 2},
+		true:  {false: 1, true: 1},
+	}
+	if !reflect.DeepEqual(matrix, expected) {
+		t.Error("Expected", expected, "got", matrix)
+	}
+	if ds.Instances[1].TargetValue != Target(false) {
+		t.Error("Expected the instances to keep their targets, got", ds.Instances[1].TargetValue)
+	}
+	if precision := Precision(matrix, true); math.Abs(precision-1.0/3) > 1e-9 {
+		t.Error("Expected", 1.0/3, "got", precision)
+	}
+	if recall := Recall(matrix, true); recall != 0.5 {
+		t.Error("Expected", 0.5, "got", recall)
+	}
+	if f1 := F1(matrix, true); math.Abs(f1-0.4) > 1e-9 {
+		t.Error("Expected", 0.4, "got", f1)
+	}
+	if accuracy := Accuracy(matrix); accuracy != 0.4 {
+		t.Error("Expected", 0.4, "got", accuracy)
+	}
+	scores, err := dtree.Score(ds, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedScores := Scores{Accuracy(matrix), Precision(matrix, true), Recall(matrix, true), F1(matrix, true)}
+	if scores != expectedScores {
+		t.Error("Expected", expectedScores, "got", scores)
+	}
+	if accuracy := Accuracy(nil); accuracy != 0 {
+		t.Error("Expected zero accuracy for no predictions, got", accuracy)
+	}
+	if precision, recall, f1 := Precision(matrix, "maybe"), Recall(matrix, "maybe"), F1(matrix, "maybe"); precision != 0 || recall != 0 || f1 != 0 {
+		t.Error("Expected zeros for an absent class, got", precision, recall, f1)
+	}
+
+	if _, err := dtree.ConfusionMatrix(ClassifiedDataSet{[]*Instance{{map[string]Feature{"salty": 2}, true, 1}}}); err == nil {
+		t.Error("Expected an error for an unseen feature value")
+	}
+
+	// Weighted instances count for their weight, in agreement with CalculateError
+	ds.Instances[0].Weight = 3 // Correctly predicted false
+	ds.Instances[3].Weight = 2 // Correctly predicted true
+	matrix, err = dtree.ConfusionMatrix(ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = map[Target]map[Target]float64{
+		false: {false: 3, true: 2},
+		true:  {false: 1, true: 2},
+	}
+	if !reflect.DeepEqual(matrix, expected) {
+		t.Error("Expected", expected, "got", matrix)
+	}
+	calculatedError, err := dtree.CalculateError(ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scores, err := dtree.Score(ds, true); err != nil || math.Abs(scores.Accuracy-(1-calculatedError)) > 1e-9 || scores.Accuracy != 0.625 {
+		t.Error("Expected an accuracy of", 1-calculatedError, "got", scores.Accuracy, err)
+	}
+	if precision, recall := Precision(matrix, true), Recall(matrix, true); precision != 0.5 || math.Abs(recall-2.0/3) > 1e-9 {
+		t.Error("Expected a precision of 0.5 and recall of", 2.0/3, "got", precision, recall)
+	}
+}
+
+func TestFeatureImportances(t *testing.T) {
+	dtree, err := Train(tennisDataSet(), BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// outlook gains 0.247 over 14 instances, while wind and humidity each gain 0.971 over 5
+	importances := dtree.FeatureImportances()
+	total := 0.0
+	for _, importance := range importances {
+		total += importance
+	}
+	if len(importances) != 3 || math.Abs(total-1) > 1e-9 {
+		t.Error("Expected importances of the 3 split features summing to 1, got", importances)
+	}
+	if math.Abs(importances["outlook"]-0.262) > 1e-3 || math.Abs(importances["wind"]-importances["humidity"]) > 1e-9 {
+		t.Error("Expected outlook at about 0.262 and equal wind and humidity, got", importances)
+	}
+	if importances := (&Decision{isOutput: true, outputValue: true}).FeatureImportances(); len(importances) != 0 {
+		t.Error("Expected no importances for a leaf, got", importances)
+	}
+}
+
+func TestDecisionClone(t *testing.T) {
+	ds := tennisDataSet()
+	dtree, err := Train(ds, BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := dtree.String()
+	clone := dtree.Clone()
+	if !reflect.DeepEqual(clone, dtree) {
+		t.Error("Expected", dtree.String(), "got", clone.String())
+	}
+	clone.PruneToDepth(0)
+	clone.SetLeafMeta("action", func(*Decision) interface{} { return "play" })
+	if !reflect.DeepEqual(dtree.String(), original) || dtree.isOutput {
+		t.Error("Expected pruning the clone to leave the original untouched, got", dtree.String())
+	}
+	if _, ok := dtree.nextDecisions[1].Meta("action"); ok {
+		t.Error("Expected the original's leaves to have no metadata")
+	}
+
+	// Values merged by MaxChildren share a child, and so should the clone's
+	merged, err := TrainWithOptions(ds, Options{MaxChildren: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clone := merged.Clone(); clone.NodeCount() != merged.NodeCount() || clone.nextDecisions[1] != clone.nextDecisions[2] {
+		t.Error("Expected the clone to keep", merged.NodeCount(), "nodes with a shared child, got", clone.NodeCount())
+	}
+}
+
+func TestDecisionEqual(t *testing.T) {
+	dtree, err := Train(tennisDataSet(), BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Without the training statistics, but making the same decisions
+	bare := &Decision{featureName: "outlook", nextDecisions: map[Feature]*Decision{
+		0: {featureName: "wind", nextDecisions: map[Feature]*Decision{
+			0: {isOutput: true, outputValue: true},
+			1: {isOutput: true, outputValue: false},
+		}},
+		1: {isOutput: true, outputValue: true},
+		2: {featureName: "humidity", nextDecisions: map[Feature]*Decision{
+			0: {isOutput: true, outputValue: true},
+			1: {isOutput: true, outputValue: false},
+		}},
+	}}
+	if !dtree.Equal(bare) || !bare.Equal(dtree) {
+		t.Error("Expected", bare.String(), "to equal", dtree.String())
+	}
+	bare.nextDecisions[2].nextDecisions[1].outputValue = true
+	if dtree.Equal(bare) {
+		t.Error("Expected a changed output to make the trees differ")
+	}
+	delete(bare.nextDecisions, 2)
+	if dtree.Equal(bare) {
+		t.Error("Expected a missing child to make the trees differ")
+	}
+
+	var none *Decision
+	if !none.Equal(nil) || none.Equal(dtree) || dtree.Equal(nil) {
+		t.Error("Expected a nil tree to only equal another nil tree")
+	}
+}
+
+func TestTreeSize(t *testing.T) {
+	dtree, err := Train(tennisDataSet(), BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// outlook, then wind under rain and humidity under sunny
+	if depth, nodes, leaves := dtree.Depth(), dtree.NodeCount(), dtree.LeafCount(); depth != 2 || nodes != 8 || leaves != 5 {
+		t.Error("Expected depth 2 with 8 nodes and 5 leaves, got", depth, nodes, leaves)
+	}
+	leaf := &Decision{isOutput: true, outputValue: true}
+	if depth, nodes, leaves := leaf.Depth(), leaf.NodeCount(), leaf.LeafCount(); depth != 0 || nodes != 1 || leaves != 1 {
+		t.Error("Expected a single leaf of depth 0, got", depth, nodes, leaves)
+	}
+	shared := &Decision{featureName: "rooms", nextDecisions: map[Feature]*Decision{0: leaf, 1: leaf, 2: leaf}}
+	if nodes, leaves := shared.NodeCount(), shared.LeafCount(); nodes != 2 || leaves != 1 {
+		t.Error("Expected a shared child to count once, got", nodes, leaves)
+	}
+}
+
+func TestBrierScore(t *testing.T) {
+	ds := flippedDataSet(200, 0.2, 1)
+	calibrated, err := TrainWithOptions(ds, Options{MaxDepth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Splits the same way, but is certain of every prediction
+	overconfident := &Decision{featureName: "signal", nextDecisions: map[Feature]*Decision{
+		0: {isOutput: true, outputValue: false},
+		1: {isOutput: true, outputValue: true},
+	}}
+	calibratedScore, err := calibrated.BrierScore(ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	overconfidentScore, err := overconfident.BrierScore(ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calibratedScore >= overconfidentScore {
+		t.Error("Expected the calibrated tree to score below", overconfidentScore, "got", calibratedScore)
+	}
+	// Certain predictions score exactly their error rate
+	if errorRate, _ := overconfident.CalculateError(ds); math.Abs(overconfidentScore-errorRate) > 1e-9 {
+		t.Error("Expected", errorRate, "got", overconfidentScore)
+	}
+
+	if _, err := calibrated.BrierScore(ClassifiedDataSet{}); err == nil {
+		t.Error("Expected an error scoring no instances")
+	}
+}
+
+func TestMultiClassTargets(t *testing.T) {
+	species := []Target{"setosa", "versicolor", "virginica"}
+	ds := ClassifiedDataSet{}
+	for i := 0; i < 30; i++ {
+		petal, sepal := i%3, i/3%2
+		ds.Instances = append(ds.Instances, &Instance{map[string]Feature{"petal": Feature(petal), "sepal": Feature(sepal)}, species[petal], 1})
+	}
+	dtree, err := Train(ds, BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trainError, err := dtree.CalculateError(ds); err != nil {
+		t.Error(err)
+	} else if trainError != 0 {
+		t.Error("Expected to separate all three classes, got error", trainError)
+	}
+	expectedTree := []string{`petal[0] ==> "setosa"`, `petal[1] ==> "versicolor"`, `petal[2] ==> "virginica"`}
+	if treeStr := dtree.String(); !reflect.DeepEqual(treeStr, expectedTree) {
 		t.Errorf("Expected %#v got %#v\n", expectedTree, treeStr)
 	}
+
+	ordered := []Target{nil, false, true, -1, 2, uint8(0), 0.5, "a", "b"}
+	for i := 1; i < len(ordered); i++ {
+		if !targetLess(ordered[i-1], ordered[i]) || targetLess(ordered[i], ordered[i-1]) {
+			t.Error("Expected", ordered[i-1], "to order before", ordered[i])
+		}
+	}
+}
+
+func TestBestFeatureGiniImpurity(t *testing.T) {
+	candy := ClassifiedDataSet{
+		[]*Instance{
+			{map[string]Feature{"salty": 0, "sweet": 0}, false, 1},
+			{map[string]Feature{"salty": 1, "sweet": 0}, false, 1},
+			{map[string]Feature{"salty": 1, "sweet": 1}, true, 1},
+			{map[string]Feature{"salty": 0, "sweet": 1}, true, 1},
+		},
+	}
+	if featureName := BestFeatureGiniImpurity(candy); featureName != "sweet" {
+		t.Error("Expected sweet, got", featureName)
+	}
+	// Outlook lowers the Gini impurity of 0.459 the most, to 0.343
+	if featureName := BestFeatureGiniImpurity(tennisDataSet()); featureName != "outlook" {
+		t.Error("Expected outlook, got", featureName)
+	}
+	if featureName := BestFeatureGiniImpurity(ClassifiedDataSet{candy.Instances[2:]}); featureName != "" {
+		t.Error("Expected no feature for a pure node, got", featureName)
+	}
+	if impurity := giniImpurity(candy.Instances); impurity != 0.5 {
+		t.Error("Expected 0.5, got", impurity)
+	}
+
+	dtree, err := Train(tennisDataSet(), BestFeatureGiniImpurity)
+	if err != nil {
+		t.Fatal(err)
+	} else if consistent, _ := dtree.IsConsistent(tennisDataSet()); !consistent || dtree.featureName != "outlook" {
+		t.Error("Expected a consistent tree rooted at outlook, got", dtree.String())
+	}
+}
+
+func TestClassifyWithFallback(t *testing.T) {
+	dtree, err := Train(tennisDataSet(), BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Never seen outlook falls back to the root majority, 9 yes to 5 no
+	inst := &Instance{map[string]Feature{"outlook": 7, "humidity": 1, "wind": 1}, false, 1}
+	if err := dtree.Classify(inst.Clone()); err == nil {
+		t.Error("Expected strict classification to fail on an unseen value")
+	}
+	if err := dtree.ClassifyWithFallback(inst); err != nil {
+		t.Error(err)
+	} else if inst.TargetValue != Target(true) {
+		t.Error("Expected the root majority true, got", inst.TargetValue)
+	}
+	// Missing humidity under sunny falls back to the sunny majority, 3 no to 2 yes
+	inst = &Instance{map[string]Feature{"outlook": 2}, true, 1}
+	if err := dtree.ClassifyWithFallback(inst); err != nil {
+		t.Error(err)
+	} else if inst.TargetValue != Target(false) {
+		t.Error("Expected the sunny majority false, got", inst.TargetValue)
+	}
+	for _, inst := range tennisDataSet().Instances {
+		classified := inst.Clone()
+		if err := dtree.ClassifyWithFallback(classified); err != nil || classified.TargetValue != inst.TargetValue {
+			t.Error("Expected", inst.TargetValue, "got", classified.TargetValue, err)
+		}
+	}
+
+	handBuilt := &Decision{featureName: "sweet", nextDecisions: map[Feature]*Decision{1: {isOutput: true, outputValue: true}}}
+	if err := handBuilt.ClassifyWithFallback(&Instance{map[string]Feature{"sweet": 0}, nil, 1}); err == nil {
+		t.Error("Expected an error without a recorded distribution to fall back on")
+	}
+}
+
+func TestPredict(t *testing.T) {
+	ds := tennisDataSet()
+	dtree, err := Train(ds, BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, inst := range ds.Instances {
+				if target, err := dtree.Predict(inst); err != nil {
+					t.Error(err)
+				} else if target != inst.TargetValue {
+					t.Error("Expected", inst.TargetValue, "got", target, "for", inst.FeatureValues)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if !reflect.DeepEqual(ds, tennisDataSet()) {
+		t.Error("Expected the instances to be left unmodified")
+	}
+	if _, err := dtree.Predict(&Instance{map[string]Feature{"outlook": 7}, nil, 1}); err == nil {
+		t.Error("Expected an error for an unseen value")
+	}
+}
+
+func TestPredictProba(t *testing.T) {
+	dtree, err := TrainWithOptions(tennisDataSet(), Options{MaxDepth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sunny := &Instance{map[string]Feature{"outlook": 2, "temp": 0, "humidity": 0, "wind": 0}, nil, 1}
+	probs, err := dtree.PredictProba(sunny)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := map[Target]float64{true: 0.4, false: 0.6}; !reflect.DeepEqual(probs, expected) {
+		t.Error("Expected", expected, "got", probs)
+	}
+	if target, _ := dtree.Predict(sunny); target != Target(false) || sunny.TargetValue != nil {
+		t.Error("Expected a prediction of false leaving the instance unclassified, got", target, sunny.TargetValue)
+	}
+	if _, err := dtree.PredictProba(&Instance{map[string]Feature{"outlook": 7}, nil, 1}); err == nil {
+		t.Error("Expected an error for an unseen value")
+	}
+}
+
+func TestPredictWithConfidence(t *testing.T) {
+	// The sunny leaf is 3 of 5 false, the overcast one entirely true
+	dtree, err := TrainWithOptions(tennisDataSet(), Options{MaxDepth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sunny := &Instance{map[string]Feature{"outlook": 2, "temp": 0, "humidity": 0, "wind": 0}, nil, 1}
+	overcast := &Instance{map[string]Feature{"outlook": 1, "temp": 0, "humidity": 0, "wind": 0}, nil, 1}
+	for _, tc := range []struct {
+		inst          *Instance
+		minConfidence float64
+		target        Target
+		confident     bool
+	}{
+		{sunny, 0.5, false, true},
+		{sunny, 0.6, false, true},
+		{sunny, 0.9, false, false},
+		{overcast, 1, true, true},
+	} {
+		target, confident, err := dtree.PredictWithConfidence(tc.inst, tc.minConfidence)
+		if err != nil {
+			t.Error(err)
+		} else if target != tc.target || confident != tc.confident {
+			t.Error("Expected", tc.target, tc.confident, "at", tc.minConfidence, "got", target, confident)
+		}
+	}
+	if sunny.TargetValue != nil {
+		t.Error("Expected the instance to be left unclassified, got", sunny.TargetValue)
+	}
+	if _, confident, err := dtree.PredictWithConfidence(&Instance{map[string]Feature{"outlook": 7}, nil, 1}, 0); err == nil || confident {
+		t.Error("Expected an unconfident error for an unseen value, got", confident, err)
+	}
+}
+
+func TestMinImpurityDecreaseFraction(t *testing.T) {
+	// Entropy prefers splitting on b, while Gini prefers a, which removes less of the root's entropy
+	var ds = ClassifiedDataSet{
+		[]*Instance{
+			{map[string]Feature{"a": 0, "b": 0}, true, 1},
+			{map[string]Feature{"a": 0, "b": 1}, true, 1},
+			{map[string]Feature{"a": 0, "b": 2}, false, 1},
+			{map[string]Feature{"a": 1, "b": 0}, false, 1},
+			{map[string]Feature{"a": 1, "b": 1}, true, 1},
+			{map[string]Feature{"a": 1, "b": 1}, false, 1},
+			{map[string]Feature{"a": 1, "b": 1}, false, 1},
+			{map[string]Feature{"a": 1, "b": 2}, false, 1},
+			{map[string]Feature{"a": 1, "b": 2}, false, 1},
+		},
+	}
+	if BestFeatureInformationGain(ds) != "b" || BestFeatureGiniImpurity(ds) != "a" {
+		t.Fatal("Expected the selectors to disagree, got", BestFeatureInformationGain(ds), BestFeatureGiniImpurity(ds))
+	}
+	bFraction := InformationGain(ds, "b") / Entropy(ds.Instances)
+	aFraction := InformationGain(ds, "a") / Entropy(ds.Instances)
+
+	// Each selector's split is kept exactly when it removes the fraction of the root's impurity, so a threshold
+	// between the two fractions keeps entropy's split but not Gini's
+	for _, tc := range []struct {
+		bf          BestFeatureFunc
+		fraction    float64
+		featureName string
+	}{
+		{BestFeatureInformationGain, 0, "b"},
+		{BestFeatureInformationGain, bFraction - 0.01, "b"},
+		{BestFeatureInformationGain, (aFraction + bFraction) / 2, "b"},
+		{BestFeatureInformationGain, bFraction + 0.01, ""},
+		{BestFeatureGiniImpurity, 0, "a"},
+		{BestFeatureGiniImpurity, aFraction - 0.01, "a"},
+		{BestFeatureGiniImpurity, (aFraction + bFraction) / 2, ""},
+		{BestFeatureGiniImpurity, aFraction + 0.01, ""},
+	} {
+		dtree, err := TrainWithOptions(ds, Options{BestFeature: tc.bf, MinImpurityDecreaseFraction: tc.fraction})
+		if err != nil {
+			t.Error("Encountered tree training error", err)
+		} else if dtree.featureName != tc.featureName || dtree.isOutput != (tc.featureName == "") {
+			t.Error("Expected a root split on", tc.featureName, "at fraction", tc.fraction, "got", dtree.String())
+		}
+	}
+}
+
+func TestMinGain(t *testing.T) {
+	// Splitting on "weak" gains about 0.19 bits, on "strong" a whole bit
+	weakDataset := ClassifiedDataSet{}
+	for i := 0; i < 8; i++ {
+		weakDataset.Instances = append(weakDataset.Instances, &Instance{map[string]Feature{"weak": Feature(i / 4)}, Target(i%4 == 0 != (i < 4)), 1})
+	}
+	strongDataset := ClassifiedDataSet{}
+	for i := 0; i < 4; i++ {
+		strongDataset.Instances = append(strongDataset.Instances, &Instance{map[string]Feature{"strong": Feature(i / 2)}, Target(i < 2), 1})
+	}
+
+	for _, tc := range []struct {
+		ds       ClassifiedDataSet
+		minGain  float64
+		isOutput bool
+	}{
+		{weakDataset, 0, false},
+		{weakDataset, 0.1, false},
+		{weakDataset, 0.2, true},
+		{strongDataset, 0.2, false},
+		{strongDataset, 1, false},
+	} {
+		dtree, err := TrainWithOptions(tc.ds, Options{MinGain: tc.minGain})
+		if err != nil {
+			t.Error("Encountered tree training error", err)
+		} else if dtree.isOutput != tc.isOutput {
+			t.Error("Expected isOutput", tc.isOutput, "at minimum gain", tc.minGain, "got", dtree.String())
+		}
+		general, err := trainGeneral(tc.ds, Options{MinGain: tc.minGain})
+		if err != nil {
+			t.Error("Encountered tree training error", err)
+		} else if general.isOutput != tc.isOutput {
+			t.Error("Expected the general path to agree at minimum gain", tc.minGain, "got", general.String())
+		}
+	}
+}
+
+func TestInformationGain(t *testing.T) {
+	ds := tennisDataSet()
+	if H := Entropy(ds.Instances); math.Abs(H-0.940) > 1e-3 {
+		t.Error("Expected an entropy of about 0.940, got", H)
+	}
+	if H := Entropy(nil); H != 0 {
+		t.Error("Expected no entropy for no instances, got", H)
+	}
+	expectedGains := map[string]float64{"outlook": 0.247, "temp": 0.029, "humidity": 0.152, "wind": 0.048}
+	for featureName, expected := range expectedGains {
+		if gain := InformationGain(ds, featureName); math.Abs(gain-expected) > 1e-3 {
+			t.Error("Expected a gain of about", expected, "for", featureName, "got", gain)
+		}
+	}
+
+	// A custom criterion built from the helpers: gain per bit of the feature's own entropy, ignoring weights
+	leastRedundant := func(ds ClassifiedDataSet) string {
+		best, bestScore := "", 0.0
+		for featureName := range ds.Instances[0].FeatureValues {
+			valueInsts := make([]*Instance, len(ds.Instances))
+			for i, inst := range ds.Instances {
+				valueInsts[i] = &Instance{TargetValue: inst.FeatureValues[featureName]}
+			}
+			if valueEntropy := Entropy(valueInsts); valueEntropy > 0 {
+				if score := InformationGain(ds, featureName) / valueEntropy; score > bestScore || score == bestScore && featureName < best {
+					best, bestScore = featureName, score
+				}
+			}
+		}
+		return best
+	}
+	if featureName := leastRedundant(ds); featureName != BestFeatureGainRatio(ds) {
+		t.Error("Expected the same pick as gain ratio, got", featureName)
+	}
+}
+
+func TestConcurrentTrain(t *testing.T) {
+	ds := tennisDataSet()
+	expectedTree, err := Train(ds, BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal("Encountered tree training error", err)
+	}
+
+	trees := make([]*Decision, 8)
+	errs := make([]error, len(trees))
+	var wg sync.WaitGroup
+	for i := range trees {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			trees[i], errs[i] = Train(ds, BestFeatureInformationGain)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range trees {
+		if errs[i] != nil {
+			t.Error("Encountered tree training error", errs[i])
+		} else if !reflect.DeepEqual(trees[i].String(), expectedTree.String()) {
+			t.Error("Expected", expectedTree.String(), "got", trees[i].String())
+		}
+	}
+	if !reflect.DeepEqual(ds, tennisDataSet()) {
+		t.Error("Expected training to leave the dataset untouched")
+	}
+}
+
+func TestSplitInformation(t *testing.T) {
+	ds := ClassifiedDataSet{}
+	for i := 0; i < 8; i++ {
+		ds.Instances = append(ds.Instances, &Instance{map[string]Feature{"uniform": Feature(i % 4), "constant": 0}, i%2 == 0, 1})
+	}
+	if splitInfo := ds.SplitInformation("uniform"); math.Abs(splitInfo-2) > 1e-9 {
+		t.Error("Expected 2 bits for a uniform 4-value feature, got", splitInfo)
+	}
+	if splitInfo := ds.SplitInformation("constant"); splitInfo != 0 {
+		t.Error("Expected 0 bits for a single-value feature, got", splitInfo)
+	}
+}
+
+func TestBestFeatureInformationGainThreshold(t *testing.T) {
+	ds := tennisDataSet()
+	if featureName := BestFeatureInformationGainThreshold(0.2)(ds); featureName != "outlook" {
+		t.Error("Expected outlook's gain of about 0.247 to pass, got", featureName)
+	}
+	if featureName := BestFeatureInformationGainThreshold(0.3)(ds); featureName != "" {
+		t.Error("Expected no feature to pass, got", featureName)
+	}
+
+	// Below outlook, the splits on wind and humidity are perfect and so keep their gain of about 0.97
+	dtree, err := Train(ds, BestFeatureInformationGainThreshold(0.2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	full, err := Train(ds, BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(dtree.String(), full.String()) {
+		t.Error("Expected", full.String(), "got", dtree.String())
+	}
+	if dtree, err = Train(ds, BestFeatureInformationGainThreshold(0.3)); err != nil {
+		t.Fatal(err)
+	} else if !dtree.isOutput || dtree.outputValue != Target(true) {
+		t.Error("Expected a single leaf predicting true, got", dtree.String())
+	}
+}
+
+func TestBestFeatureGainRatio(t *testing.T) {
+	// Both features fully determine the target, but id does so by giving every instance its own value
+	ds := ClassifiedDataSet{}
+	for i := 0; i < 8; i++ {
+		ds.Instances = append(ds.Instances, &Instance{map[string]Feature{"id": Feature(i), "signal": Feature(i % 2)}, i%2 == 0, 1})
+	}
+	if featureName := BestFeatureInformationGain(ds); featureName != "id" {
+		t.Error("Expected information gain to tie and pick id, got", featureName)
+	}
+	if featureName := BestFeatureGainRatio(ds); featureName != "signal" {
+		t.Error("Expected gain ratio to pick signal, got", featureName)
+	}
+	if featureName := BestFeatureGainRatio(ClassifiedDataSet{ds.Instances[:1]}); featureName != "" {
+		t.Error("Expected no feature for a single instance, got", featureName)
+	}
+
+	dtree, err := LimitedTrain(tennisDataSet(), BestFeatureGainRatio, 100)
+	if err != nil {
+		t.Fatal(err)
+	} else if consistent, _ := dtree.IsConsistent(tennisDataSet()); !consistent {
+		t.Error("Expected a consistent tree, got", dtree.String())
+	}
 }
 
 func TestMushroomEdibility(t *testing.T) {
@@ -178,11 +1725,11 @@ func TestMushroomEdibility(t *testing.T) {
 			}
 			inst.FeatureValues = make(map[string]Feature)
 			for i := 1; i < len(row); i++ {
+				featureName := indexToFeatureName[i]
 				if row[i] == "?" {
-					inst = nil
-					break
+					inst.FeatureValues[featureName] = FeatureMissing
+					continue
 				}
-				featureName := indexToFeatureName[i]
 				featureValue, ok := featureNameToFeatureValues[featureName][row[i]]
 				if !ok {
 					featureNameToFeatureValues[featureName][row[i]] = Feature(len(featureNameToFeatureValues[featureName]))
@@ -235,10 +1782,3 @@ func TestMushroomEdibility(t *testing.T) {
 		}
 	}
 }
-
-func shuffle(rows [][]string) {
-	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
-	for a, b := range rng.Perm(len(rows)) {
-		rows[a], rows[b] = rows[b], rows[a]
-	}
-}
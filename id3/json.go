@@ -0,0 +1,89 @@
+package id3
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// The JSON form of a tree node. Children are keyed by their feature value in decimal, since JSON object keys must be
+// strings, and output is only present on output nodes. A threshold is only present on threshold splits, whose
+// children are keyed 0 and 1.
+type decisionJSON struct {
+	Feature      string                 `json:"feature,omitempty"`
+	Threshold    *Feature               `json:"threshold,omitempty"`
+	Output       *Target                `json:"output,omitempty"`
+	Gain         float64                `json:"gain,omitempty"`
+	Distribution []targetCountJSON      `json:"distribution,omitempty"`
+	Children     map[string]*Decision   `json:"children,omitempty"`
+	Meta         map[string]interface{} `json:"meta,omitempty"`
+}
+
+// One entry of a node's target distribution. Stored as a list since targets can't be JSON object keys.
+type targetCountJSON struct {
+	Target Target  `json:"target"`
+	Count  float64 `json:"count"`
+}
+
+// Encodes the whole tree as JSON, with feature names as strings and feature values as integers. The encoding is
+// self-contained: a Vocabulary only needs to be kept alongside it when features were encoded from string labels
+// and those labels are wanted back. Leaf metadata must itself be JSON encodable.
+func (dtree *Decision) MarshalJSON() ([]byte, error) {
+	node := decisionJSON{Feature: dtree.featureName, Gain: dtree.gain, Meta: dtree.leafMeta}
+	if dtree.isOutput {
+		node.Output = &dtree.outputValue
+	}
+	if dtree.continuous {
+		node.Threshold = &dtree.threshold
+	}
+	for target, count := range dtree.distribution {
+		node.Distribution = append(node.Distribution, targetCountJSON{target, count})
+	}
+	sort.Slice(node.Distribution, func(i, j int) bool {
+		return targetLess(node.Distribution[i].Target, node.Distribution[j].Target)
+	})
+	if len(dtree.nextDecisions) > 0 {
+		node.Children = make(map[string]*Decision, len(dtree.nextDecisions))
+		for featureValue, subtree := range dtree.nextDecisions {
+			node.Children[strconv.Itoa(int(featureValue))] = subtree
+		}
+	}
+	return json.Marshal(node)
+}
+
+// Decodes a tree encoded by MarshalJSON. Targets and leaf metadata come back as generic JSON values, so bool, string
+// and float64 targets round trip exactly while other numeric targets come back as float64.
+func (dtree *Decision) UnmarshalJSON(data []byte) error {
+	var node decisionJSON
+	if err := json.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	*dtree = Decision{featureName: node.Feature, gain: node.Gain, leafMeta: node.Meta}
+	if node.Output != nil {
+		dtree.isOutput, dtree.outputValue = true, *node.Output
+	}
+	if node.Threshold != nil {
+		dtree.continuous, dtree.threshold = true, *node.Threshold
+	}
+	if node.Distribution != nil {
+		dtree.distribution = make(map[Target]float64, len(node.Distribution))
+		for _, tc := range node.Distribution {
+			dtree.distribution[tc.Target] = tc.Count
+		}
+	}
+	if node.Children != nil {
+		dtree.nextDecisions = make(map[Feature]*Decision, len(node.Children))
+		for key, subtree := range node.Children {
+			featureValue, err := strconv.Atoi(key)
+			if err != nil || featureValue < 0 || featureValue > int(^Feature(0)) {
+				return errors.New(fmt.Sprint("invalid feature value ", strconv.Quote(key), " for feature ", node.Feature))
+			} else if subtree == nil {
+				return errors.New(fmt.Sprint("missing subtree for value ", featureValue, " of feature ", node.Feature))
+			}
+			dtree.nextDecisions[Feature(featureValue)] = subtree
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package id3
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDecisionJSON(t *testing.T) {
+	// Hand built, so only the integer feature values and the tree itself are available
+	dtree := &Decision{featureName: "rooms", nextDecisions: map[Feature]*Decision{
+		1: {isOutput: true, outputValue: false},
+		2: {featureName: "floor", nextDecisions: map[Feature]*Decision{
+			0:  {isOutput: true, outputValue: true},
+			12: {isOutput: true, outputValue: false},
+		}},
+		3: {isOutput: true, outputValue: true},
+	}}
+	encoded, err := json.Marshal(dtree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded := &Decision{}
+	if err := json.Unmarshal(encoded, reloaded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reloaded, dtree) {
+		t.Error("Expected", dtree, "got", reloaded)
+	}
+
+	for _, inst := range []*Instance{
+		{map[string]Feature{"rooms": 1, "floor": 12}, false, 1},
+		{map[string]Feature{"rooms": 2, "floor": 0}, true, 1},
+		{map[string]Feature{"rooms": 2, "floor": 12}, false, 1},
+		{map[string]Feature{"rooms": 3, "floor": 5}, true, 1},
+	} {
+		classified := inst.Clone()
+		if err := reloaded.Classify(classified); err != nil {
+			t.Error(err)
+		} else if classified.TargetValue != inst.TargetValue {
+			t.Error("Expected", inst.TargetValue, "for", inst.FeatureValues, "got", classified.TargetValue)
+		}
+	}
+
+	trained, err := Train(tennisDataSet(), BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encoded, err = json.Marshal(trained); err != nil {
+		t.Fatal(err)
+	}
+	reloaded = &Decision{}
+	if err := json.Unmarshal(encoded, reloaded); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(reloaded, trained) {
+		t.Error("Expected the trained tree to round trip with its gains and distributions, got", reloaded)
+	}
+
+	if err := json.Unmarshal([]byte(`{"feature": "rooms", "children": {"many": {"output": true}}}`), &Decision{}); err == nil {
+		t.Error("Expected an error decoding a non-integer feature value")
+	}
+}
+
+func TestCandyJSONRoundTrip(t *testing.T) {
+	candy := ClassifiedDataSet{
+		[]*Instance{
+			{map[string]Feature{"salty": 0, "sweet": 0}, false, 1},
+			{map[string]Feature{"salty": 1, "sweet": 0}, false, 1},
+			{map[string]Feature{"salty": 1, "sweet": 1}, true, 1},
+			{map[string]Feature{"salty": 0, "sweet": 1}, true, 1},
+		},
+	}
+	dtree, err := Train(candy, BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := json.Marshal(dtree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reloaded *Decision
+	if err := json.Unmarshal(encoded, &reloaded); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(reloaded, dtree) {
+		t.Error("Expected", dtree, "got", reloaded)
+	}
+}
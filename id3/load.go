@@ -0,0 +1,146 @@
+package id3
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Maps each feature's original string values to the Feature codes they were encoded as, in order of first
+// appearance.
+type Vocabulary map[string]map[string]Feature
+
+// Inverts the vocabulary into feature value labels, as accepted by ToMap.
+func (v Vocabulary) Labels() map[string]map[Feature]string {
+	labels := make(map[string]map[Feature]string, len(v))
+	for featureName, codes := range v {
+		labels[featureName] = make(map[Feature]string, len(codes))
+		for label, code := range codes {
+			labels[featureName][code] = label
+		}
+	}
+	return labels
+}
+
+// Looks up the code of a feature's label, assigning the next unused one if it's new. Errors once a feature has more
+// distinct values than Feature can hold, leaving out FeatureMissing.
+func (v Vocabulary) encode(featureName, label string) (Feature, error) {
+	codes, ok := v[featureName]
+	if !ok {
+		codes = make(map[string]Feature)
+		v[featureName] = codes
+	}
+	if code, ok := codes[label]; ok {
+		return code, nil
+	} else if len(codes) >= int(FeatureMissing) {
+		return 0, errors.New(fmt.Sprint("feature ", featureName, " has more than ", len(codes), " distinct values"))
+	}
+	codes[label] = Feature(len(codes))
+	return codes[label], nil
+}
+
+// Reads a dataset in JSON lines form, one {"features": {...}, "target": ...} object per line, optionally with a
+// "weight" that otherwise defaults to 1. When encode is set,
+// feature values are labels (non-string values use their JSON text) encoded into Feature codes, and the vocabulary
+// used is returned. Otherwise feature values must already be integer codes and the vocabulary is nil. Targets are
+// kept as decoded, e.g. bools for binary data or strings naming the classes of multi-class data.
+func LoadJSONL(r io.Reader, encode bool) (ClassifiedDataSet, Vocabulary, error) {
+	var vocabulary Vocabulary
+	if encode {
+		vocabulary = make(Vocabulary)
+	}
+	ds := ClassifiedDataSet{}
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var record struct {
+			Features map[string]json.RawMessage `json:"features"`
+			Target   *Target                    `json:"target"`
+			Weight   *float64                   `json:"weight"`
+		}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			return ClassifiedDataSet{}, nil, errors.New(fmt.Sprint("line ", lineNumber, ": ", err))
+		} else if record.Target == nil {
+			return ClassifiedDataSet{}, nil, errors.New(fmt.Sprint("line ", lineNumber, ": missing target"))
+		}
+
+		inst := &Instance{FeatureValues: make(map[string]Feature, len(record.Features)), TargetValue: *record.Target, Weight: 1}
+		if record.Weight != nil {
+			inst.Weight = *record.Weight
+		}
+		for featureName, raw := range record.Features {
+			var err error
+			if encode {
+				var label string
+				if json.Unmarshal(raw, &label) != nil { // Not a string, so label it by its JSON text
+					label = string(raw)
+				}
+				inst.FeatureValues[featureName], err = vocabulary.encode(featureName, label)
+			} else {
+				var code int
+				if err = json.Unmarshal(raw, &code); err == nil && (code < 0 || code > int(^Feature(0))) {
+					err = errors.New(fmt.Sprint("feature value ", code, " out of range"))
+				}
+				inst.FeatureValues[featureName] = Feature(code)
+			}
+			if err != nil {
+				return ClassifiedDataSet{}, nil, errors.New(fmt.Sprint("line ", lineNumber, ": feature ", featureName, ": ", err))
+			}
+		}
+		ds.Instances = append(ds.Instances, inst)
+	}
+	if err := scanner.Err(); err != nil {
+		return ClassifiedDataSet{}, nil, err
+	}
+	return ds, vocabulary, nil
+}
+
+// Reads a dataset from CSV whose first row names the columns. Each targetColumn value becomes a string
+// TargetValue, and the values of every other column are encoded into Feature codes in order of first appearance,
+// keyed by column name in the returned vocabulary so predictions and trees can be decoded back to strings. Errors
+// when a column has more distinct values than Feature can hold.
+func LoadCSV(r io.Reader, targetColumn int) (ClassifiedDataSet, Vocabulary, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return ClassifiedDataSet{}, nil, err
+	} else if targetColumn < 0 || targetColumn >= len(header) {
+		return ClassifiedDataSet{}, nil, errors.New(fmt.Sprint("target column ", targetColumn, " out of range for ", len(header), " columns"))
+	}
+	seen := make(map[string]bool, len(header))
+	for _, columnName := range header {
+		if seen[columnName] {
+			return ClassifiedDataSet{}, nil, errors.New(fmt.Sprint("duplicate column name ", columnName))
+		}
+		seen[columnName] = true
+	}
+
+	vocabulary := make(Vocabulary)
+	ds := ClassifiedDataSet{}
+	for rowNumber := 2; ; rowNumber++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return ClassifiedDataSet{}, nil, err
+		}
+		inst := &Instance{FeatureValues: make(map[string]Feature, len(row)-1), TargetValue: row[targetColumn], Weight: 1}
+		for i, label := range row {
+			if i == targetColumn {
+				continue
+			}
+			if inst.FeatureValues[header[i]], err = vocabulary.encode(header[i], label); err != nil {
+				return ClassifiedDataSet{}, nil, errors.New(fmt.Sprint("row ", rowNumber, ": ", err))
+			}
+		}
+		ds.Instances = append(ds.Instances, inst)
+	}
+	return ds, vocabulary, nil
+}
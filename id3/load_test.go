@@ -0,0 +1,106 @@
+package id3
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLoadJSONL(t *testing.T) {
+	encoded := `{"features": {"outlook": "sunny", "windy": false}, "target": false}
+{"features": {"outlook": "rain", "windy": true}, "target": false}
+
+{"features": {"outlook": "sunny", "windy": true}, "target": true}
+`
+	ds, vocabulary, err := LoadJSONL(strings.NewReader(encoded), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedDataset := ClassifiedDataSet{
+		[]*Instance{
+			{map[string]Feature{"outlook": 0, "windy": 0}, false, 1},
+			{map[string]Feature{"outlook": 1, "windy": 1}, false, 1},
+			{map[string]Feature{"outlook": 0, "windy": 1}, true, 1},
+		},
+	}
+	if !reflect.DeepEqual(ds, expectedDataset) {
+		t.Error("Expected", expectedDataset, "got", ds)
+	}
+	expectedVocabulary := Vocabulary{"outlook": {"sunny": 0, "rain": 1}, "windy": {"false": 0, "true": 1}}
+	if !reflect.DeepEqual(vocabulary, expectedVocabulary) {
+		t.Error("Expected", expectedVocabulary, "got", vocabulary)
+	}
+	if labels := vocabulary.Labels(); labels["outlook"][1] != "rain" {
+		t.Error("Expected code 1 of outlook to be labeled rain, got", labels)
+	}
+
+	preEncoded := `{"features": {"outlook": 2, "windy": 0}, "target": true, "weight": 3}`
+	ds, vocabulary, err = LoadJSONL(strings.NewReader(preEncoded), false)
+	if err != nil {
+		t.Fatal(err)
+	} else if vocabulary != nil || !reflect.DeepEqual(ds.Instances[0], &Instance{map[string]Feature{"outlook": 2, "windy": 0}, true, 3}) {
+		t.Error("Expected a single pre-encoded instance, got", ds.Instances[0], vocabulary)
+	}
+
+	for _, bad := range []string{
+		`{"features": {"outlook": "sunny"}, "target": true}`,
+		`{"features": {"outlook": 70000}, "target": true}`,
+		`{"features": {"outlook": 1}}`,
+		`{"features": `,
+	} {
+		if _, _, err := LoadJSONL(strings.NewReader(bad), false); err == nil {
+			t.Error("Expected an error loading", bad)
+		}
+	}
+}
+
+func TestLoadCSV(t *testing.T) {
+	encoded := `cap,edible,odor
+flat,e,none
+bell,p,foul
+flat,p,foul
+`
+	ds, vocabulary, err := LoadCSV(strings.NewReader(encoded), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedDataset := ClassifiedDataSet{
+		[]*Instance{
+			{map[string]Feature{"cap": 0, "odor": 0}, "e", 1},
+			{map[string]Feature{"cap": 1, "odor": 1}, "p", 1},
+			{map[string]Feature{"cap": 0, "odor": 1}, "p", 1},
+		},
+	}
+	if !reflect.DeepEqual(ds, expectedDataset) {
+		t.Error("Expected", expectedDataset, "got", ds)
+	}
+	expectedVocabulary := Vocabulary{"cap": {"flat": 0, "bell": 1}, "odor": {"none": 0, "foul": 1}}
+	if !reflect.DeepEqual(vocabulary, expectedVocabulary) {
+		t.Error("Expected", expectedVocabulary, "got", vocabulary)
+	}
+
+	var wide strings.Builder
+	wide.WriteString("id,target\n")
+	for i := 0; i < 300; i++ {
+		wide.WriteString(strconv.Itoa(i) + ",x\n")
+	}
+	if ds, vocabulary, err := LoadCSV(strings.NewReader(wide.String()), 1); err != nil {
+		t.Error("Expected more than 256 values to load, got", err)
+	} else if ds.Instances[299].FeatureValues["id"] != 299 || len(vocabulary["id"]) != 300 {
+		t.Error("Expected 300 distinct codes, got", ds.Instances[299].FeatureValues, len(vocabulary["id"]))
+	}
+	wide.Reset()
+	wide.WriteString("id,target\n")
+	for i := 0; i <= int(^Feature(0))+1; i++ {
+		wide.WriteString(strconv.Itoa(i) + ",x\n")
+	}
+	for _, bad := range []string{wide.String(), "a,b\n1,2,3\n", "a,a\n1,2\n", ""} {
+		if _, _, err := LoadCSV(strings.NewReader(bad), 1); err == nil {
+			t.Error("Expected an error loading", bad[:min(len(bad), 20)])
+		}
+	}
+	if _, _, err := LoadCSV(strings.NewReader(encoded), 3); err == nil {
+		t.Error("Expected an error for an out of range target column")
+	}
+}
@@ -0,0 +1,72 @@
+package id3
+
+// Makes a trained tree respect monotonic constraints on the probability of the true target. Every node gets an
+// interval its leaves' probabilities must lie in, starting at [0, 1]. A split on a constrained feature whose
+// children's probabilities are out of order is collapsed into a leaf; otherwise the node's interval is divided
+// between the children in order, at the midpoints of their probabilities. The intervals nest: anything below a
+// child, including a further threshold split on the same continuous feature, only divides the child's interval
+// again, so the leaves below each child can never cross those of its neighbours. Leaves are clamped to their
+// interval by adjusting their target distribution.
+func (dtree *Decision) enforceMonotonic(constraints map[string]int, lo, hi float64) {
+	if dtree.isOutput {
+		dtree.clampProbability(lo, hi)
+		return
+	}
+	direction := constraints[dtree.featureName]
+	if direction == 0 {
+		for _, subtree := range dtree.nextDecisions {
+			subtree.enforceMonotonic(constraints, lo, hi)
+		}
+		return
+	}
+
+	values := sortedFeatureValues(dtree.nextDecisions)
+	if direction < 0 { // A non-increasing constraint is a non-decreasing one read in reverse
+		for i, j := 0, len(values)-1; i < j; i, j = i+1, j-1 {
+			values[i], values[j] = values[j], values[i]
+		}
+	}
+	probs := make([]float64, len(values))
+	for i, featureValue := range values {
+		probs[i] = clamp(dtree.nextDecisions[featureValue].probabilities()[Target(true)], lo, hi)
+		if i > 0 && probs[i] < probs[i-1] {
+			dtree.collapse()
+			dtree.clampProbability(lo, hi)
+			return
+		}
+	}
+	for i, featureValue := range values {
+		childLo, childHi := lo, hi
+		if i > 0 {
+			childLo = (probs[i-1] + probs[i]) / 2
+		}
+		if i < len(values)-1 {
+			childHi = (probs[i] + probs[i+1]) / 2
+		}
+		dtree.nextDecisions[featureValue].enforceMonotonic(constraints, childLo, childHi)
+	}
+}
+
+// Rescales an output node's binary target distribution so the probability of true lies within [lo, hi], keeping
+// its support. The output value follows the adjusted majority.
+func (dtree *Decision) clampProbability(lo, hi float64) {
+	stats := dtree.Stats()
+	p := dtree.probabilities()[Target(true)]
+	if stats.Support == 0 || p >= lo && p <= hi {
+		return
+	}
+	p = clamp(p, lo, hi)
+	dtree.distribution = map[Target]float64{true: p * stats.Support, false: (1 - p) * stats.Support}
+	if p != 0.5 {
+		dtree.outputValue = Target(p > 0.5)
+	}
+}
+
+func clamp(x, lo, hi float64) float64 {
+	if x < lo {
+		return lo
+	} else if x > hi {
+		return hi
+	}
+	return x
+}
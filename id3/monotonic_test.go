@@ -0,0 +1,107 @@
+package id3
+
+import (
+	"testing"
+)
+
+func TestMonotonicConstraints(t *testing.T) {
+	// The rate of true rises with income when other is 0, but dips at income 1 when other is 1
+	positives := [2][4]int{{2, 4, 6, 8}, {18, 10, 12, 19}}
+	ds := ClassifiedDataSet{}
+	for other := range positives {
+		for income, positive := range positives[other] {
+			for i := 0; i < 20; i++ {
+				ds.Instances = append(ds.Instances, &Instance{
+					map[string]Feature{"income": Feature(income), "other": Feature(other)},
+					Target(i < positive),
+					1,
+				})
+			}
+		}
+	}
+	violations := func(dtree *Decision) int {
+		count := 0
+		for other := Feature(0); other < 2; other++ {
+			previous := 0.0
+			for income := Feature(0); income < 4; income++ {
+				probs, err := dtree.ClassifyProbabilities(&Instance{FeatureValues: map[string]Feature{"income": income, "other": other}})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if probs[Target(true)] < previous {
+					count++
+				}
+				previous = probs[Target(true)]
+			}
+		}
+		return count
+	}
+
+	unconstrained, err := TrainWithOptions(ds, Options{})
+	if err != nil {
+		t.Fatal(err)
+	} else if violations(unconstrained) == 0 {
+		t.Error("Expected the unconstrained tree to violate monotonicity")
+	}
+	constrained, err := TrainWithOptions(ds, Options{MonotonicConstraints: map[string]int{"income": 1}})
+	if err != nil {
+		t.Fatal(err)
+	} else if n := violations(constrained); n != 0 {
+		t.Error("Expected the constrained tree to respect monotonicity, got", n, "violations")
+	}
+	// The monotonic branch keeps its split on income
+	if constrained.nextDecisions[0].isOutput {
+		t.Error("Expected the constrained tree to keep its monotonic split, got", constrained.String())
+	}
+}
+
+func TestMonotonicConstraintsContinuous(t *testing.T) {
+	// The rate of true mostly rises with income, but dips at 2 and 5
+	positives := []int{2, 6, 4, 10, 14, 12, 16, 18}
+	ds := ClassifiedDataSet{}
+	for income, positive := range positives {
+		for i := 0; i < 20; i++ {
+			ds.Instances = append(ds.Instances, &Instance{map[string]Feature{"income": Feature(income)}, Target(i < positive), 1})
+		}
+	}
+	violations := func(dtree *Decision) int {
+		count, previous := 0, 0.0
+		for income := range positives {
+			probs, err := dtree.ClassifyProbabilities(&Instance{FeatureValues: map[string]Feature{"income": Feature(income)}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if probs[Target(true)] < previous {
+				count++
+			}
+			previous = probs[Target(true)]
+		}
+		return count
+	}
+	// Threshold splits on income repeat down each path, each dividing the interval of the one above it
+	repeatedSplits := func(dtree *Decision) bool {
+		for _, path := range dtree.Paths() {
+			if len(path.Steps) > 1 {
+				return true
+			}
+		}
+		return false
+	}
+
+	continuous := map[string]bool{"income": true}
+	unconstrained, err := TrainWithOptions(ds, Options{ContinuousFeatures: continuous})
+	if err != nil {
+		t.Fatal(err)
+	} else if violations(unconstrained) == 0 {
+		t.Error("Expected the unconstrained tree to violate monotonicity")
+	}
+	constrained, err := TrainWithOptions(ds, Options{ContinuousFeatures: continuous, MonotonicConstraints: map[string]int{"income": 1}})
+	if err != nil {
+		t.Fatal(err)
+	} else if n := violations(constrained); n != 0 {
+		t.Error("Expected the constrained tree to respect monotonicity, got", n, "violations")
+	}
+	if !repeatedSplits(constrained) {
+		t.Error("Expected income to be split on more than once along a path, got", constrained.String())
+	}
+}
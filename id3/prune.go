@@ -0,0 +1,269 @@
+package id3
+
+import (
+	"errors"
+	"math"
+)
+
+// Lists the trees visited while pruning a tree all the way down to a single leaf, starting with a copy of the full
+// tree. Each step collapses whichever decision node leaves the least weight of validation instances misclassified,
+// into a leaf predicting its training majority; ties go to the node found first walking down from the root, in
+// order of feature value. Every tree in the sequence is a separate clone, so any of them can be picked by the
+// caller's own metric, and the receiver is left untouched.
+func (dtree *Decision) PruningSequence(validate ClassifiedDataSet) []*Decision {
+	current := dtree.Clone()
+	sequence := []*Decision{current.Clone()}
+	routing := newPruneRouting(current, validate.Instances)
+	for !current.isOutput {
+		var best *Decision
+		bestWrong := -1.0
+		for _, node := range current.decisionNodes() {
+			if wrong := routing.wrongAfterCollapse(node); bestWrong < 0 || wrong < bestWrong {
+				best, bestWrong = node, wrong
+			}
+		}
+		routing.collapse(best)
+		sequence = append(sequence, current.Clone())
+	}
+	return sequence
+}
+
+// Prunes the tree in place with CART's weakest-link method, measuring error on a validation set. Each decision node
+// costs the validation error it saves over being a leaf, divided by the number of leaves it adds; the node with the
+// lowest cost is collapsed into a leaf predicting its training majority, and this repeats while the lowest cost is
+// at most alpha. Costs are in units of error rate, weighted as from CalculateError. Returns the cost of each
+// collapse in order, so pruning a clone with an infinite alpha lists every alpha worth trying.
+func (dtree *Decision) CostComplexityPrune(validate ClassifiedDataSet, alpha float64) ([]float64, error) {
+	if len(validate.Instances) == 0 {
+		return nil, errors.New("no instances provided")
+	}
+	var alphas []float64
+	routing := newPruneRouting(dtree, validate.Instances)
+	for !dtree.isOutput {
+		var weakest *Decision
+		weakestAlpha := math.Inf(1)
+		for _, node := range dtree.decisionNodes() {
+			saved := routing.wrongAfterCollapse(node) - routing.wrong // Weight the subtree gets right that a leaf wouldn't
+			added := math.Max(float64(node.LeafCount()-1), 1)
+			if nodeAlpha := saved / totalWeight(validate.Instances) / added; weakest == nil || nodeAlpha < weakestAlpha {
+				weakest, weakestAlpha = node, nodeAlpha
+			}
+		}
+		if weakestAlpha > alpha {
+			break
+		}
+		routing.collapse(weakest)
+		alphas = append(alphas, weakestAlpha)
+	}
+	return alphas, nil
+}
+
+// Prunes the tree in place so no decision is taken more than maxDepth splits below the root, like training with
+// Options.MaxDepth would. Nodes at maxDepth become leaves predicting the majority target of the training instances
+// that reached them, so a maxDepth of zero leaves a single leaf.
+func (dtree *Decision) PruneToDepth(maxDepth int) {
+	if dtree.isOutput {
+		return
+	} else if maxDepth <= 0 {
+		dtree.collapse()
+		return
+	}
+	for _, subtree := range dtree.nextDecisions {
+		subtree.PruneToDepth(maxDepth - 1)
+	}
+}
+
+// Prunes the tree in place with Quinlan's pessimistic error pruning, which judges subtrees on the training counts
+// kept at each node rather than on a validation set. Walking down from the root, a decision node is collapsed into
+// a leaf predicting its training majority if the leaf's training errors, plus a continuity correction of a half,
+// are within one standard error of its subtree's errors plus a half per leaf. Nodes without training counts, like
+// those of hand-built trees, are left as they are.
+func (dtree *Decision) PessimisticPrune() {
+	if dtree.isOutput {
+		return
+	}
+	support := dtree.Stats().Support
+	if support > 0 {
+		leafErrors := support - dtree.distribution[dtree.MajorityTarget()] + 0.5
+		subtreeErrors := dtree.pessimisticErrors()
+		standardError := math.Sqrt(subtreeErrors * math.Max(support-subtreeErrors, 0) / support)
+		if leafErrors <= subtreeErrors+standardError {
+			dtree.collapse()
+			return
+		}
+	}
+	for _, subtree := range dtree.uniqueChildren() {
+		subtree.PessimisticPrune()
+	}
+}
+
+// Sums the training errors of the subtree's leaves, with a continuity correction of a half per leaf.
+func (dtree *Decision) pessimisticErrors() float64 {
+	if dtree.isOutput {
+		return dtree.Stats().Support - dtree.distribution[dtree.outputValue] + 0.5
+	}
+	sum := 0.0
+	for _, subtree := range dtree.uniqueChildren() {
+		sum += subtree.pessimisticErrors()
+	}
+	return sum
+}
+
+// Caches where each validation instance is routed in a tree being pruned, so that the effect of collapsing a node
+// can be found from just the instances passing through it rather than by classifying every instance again.
+// Instances missing values are voted on by several leaves, so they pass through every node along the branches they
+// follow, and are classified again whenever one of those nodes is collapsed.
+type pruneRouting struct {
+	root        *Decision
+	insts       []*Instance
+	through     map[*Decision][]int // Indexes of the instances passing through each decision node
+	predictions []Target
+	classified  []bool  // Instances that get stuck at a decision node can't be classified, and count as wrong
+	voting      []bool  // Instances with missing values, whose predictions combine several leaves
+	wrong       float64 // Total weight of the misclassified instances
+}
+
+func newPruneRouting(dtree *Decision, insts []*Instance) *pruneRouting {
+	r := &pruneRouting{
+		root:        dtree,
+		insts:       insts,
+		through:     make(map[*Decision][]int),
+		predictions: make([]Target, len(insts)),
+		classified:  make([]bool, len(insts)),
+		voting:      make([]bool, len(insts)),
+	}
+	for i, inst := range insts {
+		if hasMissingValue(inst.FeatureValues) {
+			r.voting[i] = true
+			r.routeVoting(dtree, i, make(map[*Decision]bool))
+			r.predictions[i], r.classified[i] = r.predict(i)
+		} else {
+			node := dtree
+			for !node.isOutput {
+				r.through[node] = append(r.through[node], i)
+				value, ok := inst.FeatureValues[node.featureName]
+				if !ok {
+					break
+				}
+				if node, _ = node.nextDecision(value); node == nil {
+					break
+				}
+			}
+			if node != nil && node.isOutput {
+				r.predictions[i], r.classified[i] = node.outputValue, true
+			}
+		}
+		if r.isWrong(i) {
+			r.wrong += inst.weight()
+		}
+	}
+	return r
+}
+
+// Records a voting instance as passing through every decision node it reaches, following each branch of the nodes
+// whose feature it is missing.
+func (r *pruneRouting) routeVoting(node *Decision, i int, seen map[*Decision]bool) {
+	if node.isOutput || seen[node] {
+		return
+	}
+	seen[node] = true
+	r.through[node] = append(r.through[node], i)
+	value, ok := r.insts[i].FeatureValues[node.featureName]
+	if !ok {
+		return
+	} else if value == FeatureMissing {
+		for _, subtree := range node.uniqueChildren() {
+			r.routeVoting(subtree, i, seen)
+		}
+	} else if nextDecision, ok := node.nextDecision(value); ok {
+		r.routeVoting(nextDecision, i, seen)
+	}
+}
+
+// Classifies an instance with the tree as it currently is, as Predict would.
+func (r *pruneRouting) predict(i int) (Target, bool) {
+	target, err := r.root.predict(r.insts[i].FeatureValues)
+	return target, err == nil
+}
+
+func (r *pruneRouting) isWrong(i int) bool {
+	return !r.classified[i] || r.predictions[i] != r.insts[i].TargetValue
+}
+
+// Weighs the instances the tree would misclassify with node collapsed into a leaf, leaving the tree as it is.
+// Voting instances are classified with the node briefly collapsed, and it is restored before returning.
+func (r *pruneRouting) wrongAfterCollapse(node *Decision) float64 {
+	output := node.MajorityTarget()
+	wrong := r.wrong
+	saved := *node
+	node.isOutput, node.outputValue = true, output
+	for _, i := range r.through[node] {
+		weight := r.insts[i].weight()
+		if r.isWrong(i) {
+			wrong -= weight
+		}
+		if r.voting[i] {
+			if target, ok := r.predict(i); !ok || target != r.insts[i].TargetValue {
+				wrong += weight
+			}
+		} else if output != r.insts[i].TargetValue {
+			wrong += weight
+		}
+	}
+	*node = saved
+	return wrong
+}
+
+// Collapses node into a leaf, updating the predictions of the instances passing through it. The nodes below it
+// leave the tree, so their cached instances are never consulted again.
+func (r *pruneRouting) collapse(node *Decision) {
+	r.wrong = r.wrongAfterCollapse(node)
+	node.collapse()
+	for _, i := range r.through[node] {
+		if r.voting[i] {
+			r.predictions[i], r.classified[i] = r.predict(i)
+		} else {
+			r.predictions[i], r.classified[i] = node.outputValue, true
+		}
+	}
+	delete(r.through, node)
+}
+
+// Lists the tree's decision nodes in pre-order, following children in order of feature value. Nodes shared by
+// several feature values are listed once.
+func (dtree *Decision) decisionNodes() []*Decision {
+	var nodes []*Decision
+	seen := make(map[*Decision]bool)
+	var visit func(node *Decision)
+	visit = func(node *Decision) {
+		if node.isOutput || seen[node] {
+			return
+		}
+		seen[node] = true
+		nodes = append(nodes, node)
+		for _, featureValue := range sortedFeatureValues(node.nextDecisions) {
+			visit(node.nextDecisions[featureValue])
+		}
+	}
+	visit(dtree)
+	return nodes
+}
+
+// Turns a decision node into an output node predicting its majority target.
+func (dtree *Decision) collapse() {
+	dtree.outputValue = dtree.MajorityTarget()
+	dtree.isOutput, dtree.nextDecisions, dtree.featureName, dtree.gain = true, nil, "", 0
+	dtree.continuous, dtree.threshold = false, 0
+}
+
+// Weighs the instances the tree classifies wrongly, including those it can't classify at all. The instances
+// aren't modified.
+func (dtree *Decision) misclassified(insts []*Instance) float64 {
+	wrong := 0.0
+	for _, inst := range insts {
+		if target, err := dtree.Predict(inst); err != nil || target != inst.TargetValue {
+			wrong += inst.weight()
+		}
+	}
+	return wrong
+}
@@ -0,0 +1,280 @@
+package id3
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// Counts the nodes of a tree, decision and output alike.
+func countNodes(dtree *Decision) int {
+	count := 1
+	for _, subtree := range dtree.nextDecisions {
+		count += countNodes(subtree)
+	}
+	return count
+}
+
+// PruningSequence as it would be without cached routing, reclassifying every validation instance for every
+// candidate collapse.
+func naivePruningSequence(dtree *Decision, validate ClassifiedDataSet) []*Decision {
+	current := dtree.Clone()
+	sequence := []*Decision{current.Clone()}
+	for !current.isOutput {
+		var best *Decision
+		bestWrong := -1.0
+		for _, node := range current.decisionNodes() {
+			saved := *node
+			node.collapse()
+			if wrong := current.misclassified(validate.Instances); bestWrong < 0 || wrong < bestWrong {
+				best, bestWrong = node, wrong
+			}
+			*node = saved
+		}
+		best.collapse()
+		sequence = append(sequence, current.Clone())
+	}
+	return sequence
+}
+
+func TestPruningSequence(t *testing.T) {
+	ds := flippedDataSet(200, 0.2, 1)
+	train, validate := ClassifiedDataSet{ds.Instances[:100]}, ClassifiedDataSet{ds.Instances[100:]}
+	dtree, err := Train(train, BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := dtree.Clone()
+
+	sequence := dtree.PruningSequence(validate)
+	if len(sequence) < 2 || !reflect.DeepEqual(sequence[0], dtree) {
+		t.Fatal("Expected the sequence to start with the full tree, got", len(sequence), "trees")
+	}
+	for i := 1; i < len(sequence); i++ {
+		if countNodes(sequence[i]) >= countNodes(sequence[i-1]) {
+			t.Error("Expected tree", i, "to have fewer nodes than", countNodes(sequence[i-1]), "got", countNodes(sequence[i]))
+		}
+	}
+	if last := sequence[len(sequence)-1]; !last.isOutput {
+		t.Error("Expected the sequence to end at a single leaf, got", last.String())
+	}
+	if !reflect.DeepEqual(dtree, original) {
+		t.Error("Expected the pruned tree to be left untouched")
+	}
+	if !reflect.DeepEqual(sequence, naivePruningSequence(dtree, validate)) {
+		t.Error("Expected cached routing to prune the same way as reclassifying everything")
+	}
+}
+
+func TestPruningSequenceMissingValues(t *testing.T) {
+	ds := randomBinaryDataSet(400, 6, 1)
+	train, validate := ClassifiedDataSet{ds.Instances[:200]}, ClassifiedDataSet{ds.Instances[200:]}
+	dtree, err := Train(train, BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, inst := range validate.Instances {
+		if i%4 == 0 {
+			inst.FeatureValues[string(rune('a'+i%3))] = FeatureMissing
+		}
+	}
+	if wrong, expected := newPruneRouting(dtree, validate.Instances).wrong, dtree.misclassified(validate.Instances); wrong != expected {
+		t.Error("Expected", expected, "misclassified, got", wrong)
+	}
+	if !reflect.DeepEqual(dtree.PruningSequence(validate), naivePruningSequence(dtree, validate)) {
+		t.Error("Expected cached routing to vote on missing values as reclassifying everything does")
+	}
+}
+
+func TestPruneRoutingUnknownFeature(t *testing.T) {
+	dtree := &Decision{featureName: "a", nextDecisions: map[Feature]*Decision{
+		0: {isOutput: true, outputValue: true},
+		1: {isOutput: true, outputValue: false},
+	}}
+	// Without a value for a, the instance can't be classified, even though the leaf for 0 would get it right
+	insts := []*Instance{{map[string]Feature{"b": 0}, true, 1}}
+	if wrong, expected := newPruneRouting(dtree, insts).wrong, dtree.misclassified(insts); wrong != expected {
+		t.Error("Expected", expected, "misclassified, got", wrong)
+	}
+}
+
+func TestCostComplexityPrune(t *testing.T) {
+	ds := flippedDataSet(200, 0.2, 1)
+	train, validate := ClassifiedDataSet{ds.Instances[:100]}, ClassifiedDataSet{ds.Instances[100:]}
+	dtree, err := Train(train, BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unpruned := dtree.Clone()
+	if alphas, err := unpruned.CostComplexityPrune(validate, math.Inf(-1)); err != nil {
+		t.Fatal(err)
+	} else if len(alphas) != 0 || !reflect.DeepEqual(unpruned, dtree) {
+		t.Error("Expected no pruning below every cost, got", alphas)
+	}
+
+	full := dtree.Clone()
+	alphas, err := full.CostComplexityPrune(validate, math.Inf(1))
+	if err != nil {
+		t.Fatal(err)
+	} else if !full.isOutput || len(alphas) == 0 {
+		t.Error("Expected an infinite alpha to collapse the tree to a leaf, got", alphas)
+	}
+
+	// Collapsing where it costs nothing can only help on the validation set
+	pruned := dtree.Clone()
+	if _, err := pruned.CostComplexityPrune(validate, 0); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := dtree.CalculateError(validate)
+	after, _ := pruned.CalculateError(validate)
+	if after > before || countNodes(pruned) >= countNodes(dtree) {
+		t.Error("Expected a smaller tree with at most", before, "error, got", countNodes(pruned), "nodes with", after)
+	}
+	if !pruned.isOutput && pruned.featureName != "signal" {
+		t.Error("Expected the signal split to survive, got", pruned.String())
+	}
+
+	if _, err := dtree.CostComplexityPrune(ClassifiedDataSet{}, 0); err == nil {
+		t.Error("Expected an error pruning without validation instances")
+	}
+}
+
+func TestPruneWeightedValidation(t *testing.T) {
+	ds := randomBinaryDataSet(400, 6, 3)
+	train := ClassifiedDataSet{ds.Instances[:200]}
+	dtree, err := Train(train, BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Weighing every other instance double should prune exactly as duplicating it does
+	weighted, duplicated := ClassifiedDataSet{}, ClassifiedDataSet{}
+	for i, inst := range ds.Instances[200:] {
+		weighted.Instances = append(weighted.Instances, inst.Clone())
+		duplicated.Instances = append(duplicated.Instances, inst)
+		if i%2 == 0 {
+			weighted.Instances[i].Weight = 2
+			duplicated.Instances = append(duplicated.Instances, inst)
+		}
+	}
+	if !reflect.DeepEqual(dtree.PruningSequence(weighted), dtree.PruningSequence(duplicated)) {
+		t.Error("Expected weighted instances to pick the same collapses as duplicated ones")
+	}
+	weightedAlphas, err := dtree.Clone().CostComplexityPrune(weighted, math.Inf(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	duplicatedAlphas, err := dtree.Clone().CostComplexityPrune(duplicated, math.Inf(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(weightedAlphas) != len(duplicatedAlphas) {
+		t.Fatal("Expected", duplicatedAlphas, "got", weightedAlphas)
+	}
+	for i := range weightedAlphas {
+		if math.Abs(weightedAlphas[i]-duplicatedAlphas[i]) > 1e-12 {
+			t.Error("Expected", duplicatedAlphas, "got", weightedAlphas)
+			break
+		}
+	}
+}
+
+func TestPruneToDepth(t *testing.T) {
+	dtree, err := Train(tennisDataSet(), BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shallow, err := TrainWithOptions(tennisDataSet(), Options{MaxDepth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pruned := dtree.Clone()
+	pruned.PruneToDepth(1)
+	if !reflect.DeepEqual(pruned, shallow) {
+		t.Error("Expected", shallow.String(), "got", pruned.String())
+	}
+
+	pruned = dtree.Clone()
+	pruned.PruneToDepth(2)
+	if !reflect.DeepEqual(pruned, dtree) {
+		t.Error("Expected a depth 2 tree to be left as it is, got", pruned.String())
+	}
+	pruned.PruneToDepth(0)
+	if !pruned.isOutput || pruned.outputValue != Target(true) {
+		t.Error("Expected a single leaf predicting true, got", pruned.String())
+	}
+}
+
+func TestPessimisticPrune(t *testing.T) {
+	// Every leaf of the tennis tree is pure, and each is backed by enough instances to keep
+	dtree, err := Train(tennisDataSet(), BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pruned := dtree.Clone()
+	pruned.PessimisticPrune()
+	if !pruned.Equal(dtree) {
+		t.Error("Expected the tennis tree to be left as it is, got", pruned.String())
+	}
+
+	// A split isolating a single exception isn't worth its extra leaf
+	ds := ClassifiedDataSet{}
+	for i := 0; i < 10; i++ {
+		ds.Instances = append(ds.Instances, &Instance{map[string]Feature{"a": Feature(i / 5), "b": Feature(i / 9)}, Target(i < 9), 1})
+	}
+	dtree, err = Train(ds, BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dtree.PessimisticPrune()
+	if !dtree.isOutput || dtree.outputValue != Target(true) {
+		t.Error("Expected a single leaf predicting true, got", dtree.String())
+	}
+
+	noisy := multiValuedDataSet(500, 6, 1)
+	dtree, err = Train(noisy, BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pruned = dtree.Clone()
+	pruned.PessimisticPrune()
+	if pruned.isOutput || countNodes(pruned) >= countNodes(dtree) {
+		t.Error("Expected fewer than", countNodes(dtree), "nodes, got", countNodes(pruned))
+	}
+
+	bare := &Decision{featureName: "a", nextDecisions: map[Feature]*Decision{
+		0: {isOutput: true, outputValue: true},
+		1: {isOutput: true, outputValue: false},
+	}}
+	bare.PessimisticPrune()
+	if bare.isOutput {
+		t.Error("Expected a tree without training counts to be left as it is")
+	}
+}
+
+// A deep tree and a large validation set, where reclassifying everything for every candidate is costly.
+func pruningBenchmarkData(b *testing.B) (*Decision, ClassifiedDataSet) {
+	ds := randomBinaryDataSet(4000, 12, 1)
+	train, validate := ClassifiedDataSet{ds.Instances[:2000]}, ClassifiedDataSet{ds.Instances[2000:]}
+	dtree, err := Train(train, BestFeatureInformationGain)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return dtree, validate
+}
+
+func BenchmarkPruningSequence(b *testing.B) {
+	dtree, validate := pruningBenchmarkData(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dtree.PruningSequence(validate)
+	}
+}
+
+func BenchmarkPruningSequenceNaive(b *testing.B) {
+	dtree, validate := pruningBenchmarkData(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		naivePruningSequence(dtree, validate)
+	}
+}
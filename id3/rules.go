@@ -0,0 +1,246 @@
+package id3
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// How a FeatureStep compares a feature with its Value.
+type Comparison uint8
+
+const (
+	EqualTo     Comparison = iota // The feature has exactly Value
+	AtMost                        // The feature is at most Value, on the lower side of a threshold split
+	GreaterThan                   // The feature is greater than Value, on the upper side of a threshold split
+)
+
+// One test on the way down a decision tree: the named feature must compare with the given value as specified.
+type FeatureStep struct {
+	FeatureName string
+	Value       Feature
+	Comparison  Comparison
+}
+
+// Checks whether a feature value passes the step's test.
+func (step FeatureStep) holds(value Feature) bool {
+	switch step.Comparison {
+	case AtMost:
+		return value <= step.Value
+	case GreaterThan:
+		return value > step.Value
+	}
+	return value == step.Value
+}
+
+// Describes the step's test for display, e.g. 2, <=2 or >2.
+func (step FeatureStep) condition() string {
+	switch step.Comparison {
+	case AtMost:
+		return "<=" + strconv.Itoa(int(step.Value))
+	case GreaterThan:
+		return ">" + strconv.Itoa(int(step.Value))
+	}
+	return strconv.Itoa(int(step.Value))
+}
+
+// A route from the root of a decision tree to one of its output nodes.
+type Path struct {
+	Steps  []FeatureStep
+	Output Target
+}
+
+// Lists every root-to-leaf path of the tree as structured data, in order of feature value.
+func (dtree *Decision) Paths() []Path {
+	var paths []Path
+	dtree.walkLeaves(nil, func(steps []FeatureStep, leaf *Decision) {
+		paths = append(paths, Path{Steps: append([]FeatureStep{}, steps...), Output: leaf.outputValue})
+	})
+	return paths
+}
+
+// A conjunction of feature tests, all of which must hold for the rule to apply, and the target it implies.
+type Rule struct {
+	Conditions []FeatureStep
+	Output     Target
+}
+
+// Checks whether every condition of the rule holds for the provided feature values.
+func (r Rule) Matches(features map[string]Feature) bool {
+	for _, cond := range r.Conditions {
+		if value, ok := features[cond.FeatureName]; !ok || !cond.holds(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// An ordered decision list. Rules are tried in order and the first one that matches decides the target, so a
+// rule with no conditions acts as a default.
+type RuleList []Rule
+
+// Converts a decision tree into a decision list. The most common leaf output becomes the default rule at the end,
+// and only leaves predicting something else need a rule of their own, shortest rules first.
+func (dtree *Decision) ToRuleList() RuleList {
+	var rules RuleList
+	var defaultOutput Target
+	outputCounts := make(map[Target]int)
+	for _, path := range dtree.Paths() {
+		rules = append(rules, Rule{Conditions: path.Steps, Output: path.Output})
+		outputCounts[path.Output]++
+		if outputCounts[path.Output] > outputCounts[defaultOutput] {
+			defaultOutput = path.Output
+		}
+	}
+
+	// Rules that agree with the default are redundant since they'd fall through to it anyway
+	list := make(RuleList, 0, len(rules)+1)
+	for _, rule := range rules {
+		if rule.Output != defaultOutput {
+			list = append(list, rule)
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return len(list[i].Conditions) < len(list[j].Conditions)
+	})
+	return append(list, Rule{Output: defaultOutput})
+}
+
+// Attempt to classify a provided instance of data with the first matching rule. Like Decision.Classify, the
+// classification is set in the instance's TargetValue field.
+func (rl RuleList) Classify(inst *Instance) error {
+	for _, rule := range rl {
+		if rule.Matches(inst.FeatureValues) {
+			inst.TargetValue = rule.Output // Previous value is overwritten
+			return nil
+		}
+	}
+	return errors.New(fmt.Sprint("no rule matches feature values ", inst.FeatureValues))
+}
+
+// A rule of a tree along with how it fares on a dataset.
+type RankedRule struct {
+	Rule
+	Coverage float64 // Fraction of the dataset's instances the rule applies to
+	Accuracy float64 // Fraction of the covered instances whose target is the rule's output, 0 if none are covered
+}
+
+// Turns each root-to-leaf path of the tree into a rule, ranked for presentation by how much of a dataset it
+// covers and then by how accurate it is there, both descending.
+func (dtree *Decision) RankedRules(ds ClassifiedDataSet) []RankedRule {
+	var ranked []RankedRule
+	for _, path := range dtree.Paths() {
+		rr := RankedRule{Rule: Rule{Conditions: path.Steps, Output: path.Output}}
+		covered, correct := 0, 0
+		for _, inst := range ds.Instances {
+			if rr.Matches(inst.FeatureValues) {
+				covered++
+				if inst.TargetValue == rr.Output {
+					correct++
+				}
+			}
+		}
+		if len(ds.Instances) > 0 {
+			rr.Coverage = float64(covered) / float64(len(ds.Instances))
+		}
+		if covered > 0 {
+			rr.Accuracy = float64(correct) / float64(covered)
+		}
+		ranked = append(ranked, rr)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Coverage != ranked[j].Coverage {
+			return ranked[i].Coverage > ranked[j].Coverage
+		}
+		return ranked[i].Accuracy > ranked[j].Accuracy
+	})
+	return ranked
+}
+
+// Describes every root-to-leaf path of the tree as a readable rule, e.g. "IF outlook = 2 AND humidity = 1 THEN
+// false", in order of feature value. A lone leaf gives a rule without conditions. An optional decoder, like ToMap's,
+// maps feature values back to their original labels; values without a label, and thresholds, are written in
+// decimal.
+func (dtree *Decision) Rules(decoder ...map[string]map[Feature]string) []string {
+	var featureLabels map[string]map[Feature]string
+	if len(decoder) > 0 {
+		featureLabels = decoder[0]
+	}
+	var rules []string
+	dtree.WalkLeaves(func(path []FeatureStep, value Target) {
+		conditions := make([]string, len(path))
+		for i, step := range path {
+			switch step.Comparison {
+			case AtMost:
+				conditions[i] = step.FeatureName + " <= " + strconv.Itoa(int(step.Value))
+			case GreaterThan:
+				conditions[i] = step.FeatureName + " > " + strconv.Itoa(int(step.Value))
+			default:
+				conditions[i] = step.FeatureName + " = " + featureLabel(featureLabels, step.FeatureName, step.Value)
+			}
+		}
+		if len(conditions) == 0 {
+			rules = append(rules, fmt.Sprint("THEN ", value))
+		} else {
+			rules = append(rules, fmt.Sprint("IF ", strings.Join(conditions, " AND "), " THEN ", value))
+		}
+	})
+	return rules
+}
+
+// Calls fn for every leaf of the tree, in order of feature value, with the steps leading to it and its output
+// value. This is the traversal behind Paths and String, and is the way to build other exports without going
+// through strings. The path slice is reused between calls, so fn must copy it to keep it.
+func (dtree *Decision) WalkLeaves(fn func(path []FeatureStep, value Target)) {
+	dtree.walkLeaves(nil, func(path []FeatureStep, leaf *Decision) {
+		fn(path, leaf.outputValue)
+	})
+}
+
+// Finds the node reached from the root by following the child for each feature value of path in turn, e.g. to
+// inspect part of a large tree. At a threshold split, 0 follows the side at most the threshold and 1 the side
+// above it. An empty path gives the root. Errors if the path goes past an output node or a value has no child.
+func (dtree *Decision) Subtree(path []Feature) (*Decision, error) {
+	node := dtree
+	for i, featureValue := range path {
+		if node.isOutput {
+			return nil, errors.New(fmt.Sprint("path continues past an output node at step ", i))
+		}
+		next, ok := node.nextDecisions[featureValue]
+		if !ok {
+			return nil, errors.New(fmt.Sprint("no child for value ", featureValue, " of feature ", node.featureName, " at step ", i))
+		}
+		node = next
+	}
+	return node, nil
+}
+
+// Recursively visits every output node in order of feature value, passing the steps taken to reach it. The path
+// slice is reused between calls, so fn must copy it to keep it.
+func (dtree *Decision) walkLeaves(path []FeatureStep, fn func(path []FeatureStep, leaf *Decision)) {
+	if dtree.isOutput {
+		fn(path, dtree)
+		return
+	}
+	for _, featureValue := range sortedFeatureValues(dtree.nextDecisions) {
+		step := FeatureStep{dtree.featureName, featureValue, EqualTo}
+		if dtree.continuous && featureValue == 0 {
+			step = FeatureStep{dtree.featureName, dtree.threshold, AtMost}
+		} else if dtree.continuous {
+			step = FeatureStep{dtree.featureName, dtree.threshold, GreaterThan}
+		}
+		dtree.nextDecisions[featureValue].walkLeaves(append(path, step), fn)
+	}
+}
+
+// Lists the keys of a node's children in ascending order, for deterministic traversal.
+func sortedFeatureValues(nextDecisions map[Feature]*Decision) []Feature {
+	featureValues := make([]Feature, 0, len(nextDecisions))
+	for featureValue := range nextDecisions {
+		featureValues = append(featureValues, featureValue)
+	}
+	sort.Slice(featureValues, func(i, j int) bool { return featureValues[i] < featureValues[j] })
+	return featureValues
+}
@@ -0,0 +1,177 @@
+package id3
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestToRuleList(t *testing.T) {
+	dtree, err := Train(tennisDataSet(), BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal("Encountered tree training error", err)
+	}
+	rules := dtree.ToRuleList()
+
+	// Five leaves, three of which predict the default
+	if len(rules) != 3 {
+		t.Error("Expected 3 rules, got", rules)
+	} else if last := rules[len(rules)-1]; len(last.Conditions) != 0 || last.Output != Target(true) {
+		t.Error("Expected a default rule predicting true, got", last)
+	}
+
+	for _, inst := range tennisDataSet().Instances {
+		treeInst, listInst := inst.Clone(), inst.Clone()
+		if err := dtree.Classify(treeInst); err != nil {
+			t.Error(err)
+		} else if err := rules.Classify(listInst); err != nil {
+			t.Error(err)
+		} else if treeInst.TargetValue != listInst.TargetValue {
+			t.Error("Rule list disagrees with tree on", inst.FeatureValues)
+		}
+	}
+
+	if err := (RuleList{}).Classify(tennisDataSet().Instances[0]); err == nil {
+		t.Error("Expected an error when no rule matches")
+	}
+}
+
+func TestPaths(t *testing.T) {
+	dtree, err := Train(tennisDataSet(), BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal("Encountered tree training error", err)
+	}
+
+	expectedPaths := []Path{
+		{[]FeatureStep{{"outlook", 0, EqualTo}, {"wind", 0, EqualTo}}, true},
+		{[]FeatureStep{{"outlook", 0, EqualTo}, {"wind", 1, EqualTo}}, false},
+		{[]FeatureStep{{"outlook", 1, EqualTo}}, true},
+		{[]FeatureStep{{"outlook", 2, EqualTo}, {"humidity", 0, EqualTo}}, true},
+		{[]FeatureStep{{"outlook", 2, EqualTo}, {"humidity", 1, EqualTo}}, false},
+	}
+	if paths := dtree.Paths(); !reflect.DeepEqual(paths, expectedPaths) {
+		t.Errorf("Expected %v got %v", expectedPaths, paths)
+	}
+
+	leaf := &Decision{isOutput: true, outputValue: Target(true)}
+	if paths := leaf.Paths(); len(paths) != 1 || len(paths[0].Steps) != 0 || paths[0].Output != Target(true) {
+		t.Error("Expected a single empty path for a lone leaf, got", paths)
+	}
+}
+
+func TestWalkLeaves(t *testing.T) {
+	dtree, err := Train(tennisDataSet(), BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal("Encountered tree training error", err)
+	}
+
+	// A SQL CASE expression, one WHEN per leaf
+	var whens []string
+	dtree.WalkLeaves(func(path []FeatureStep, value Target) {
+		conditions := make([]string, len(path))
+		for i, step := range path {
+			conditions[i] = fmt.Sprint(step.FeatureName, " = ", step.Value)
+		}
+		whens = append(whens, fmt.Sprint("WHEN ", strings.Join(conditions, " AND "), " THEN ", value))
+	})
+	expectedWhens := []string{
+		"WHEN outlook = 0 AND wind = 0 THEN true",
+		"WHEN outlook = 0 AND wind = 1 THEN false",
+		"WHEN outlook = 1 THEN true",
+		"WHEN outlook = 2 AND humidity = 0 THEN true",
+		"WHEN outlook = 2 AND humidity = 1 THEN false",
+	}
+	if !reflect.DeepEqual(whens, expectedWhens) {
+		t.Error("Expected", expectedWhens, "got", whens)
+	}
+}
+
+func TestSubtree(t *testing.T) {
+	dtree, err := Train(tennisDataSet(), BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal("Encountered tree training error", err)
+	}
+	sunny, err := dtree.Subtree([]Feature{2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedTree := []string{`humidity[0] ==> true`, `humidity[1] ==> false`}
+	if treeStr := sunny.String(); !reflect.DeepEqual(treeStr, expectedTree) {
+		t.Error("Expected", expectedTree, "got", treeStr)
+	}
+	if leaf, err := dtree.Subtree([]Feature{0, 1}); err != nil || !leaf.isOutput || leaf.outputValue != Target(false) {
+		t.Error("Expected the rainy, windy leaf predicting false, got", leaf, err)
+	}
+	if root, err := dtree.Subtree(nil); err != nil || root != dtree {
+		t.Error("Expected an empty path to give the root, got", root, err)
+	}
+
+	for _, path := range [][]Feature{{1, 0}, {3}, {2, 2}} {
+		if _, err := dtree.Subtree(path); err == nil {
+			t.Error("Expected an error following", path)
+		}
+	}
+}
+
+func TestRules(t *testing.T) {
+	dtree, err := Train(tennisDataSet(), BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal("Encountered tree training error", err)
+	}
+	labels := map[string]map[Feature]string{
+		"outlook": {0: "rain", 1: "overcast", 2: "sunny"},
+		"wind":    {0: "weak", 1: "strong"},
+	}
+	expectedRules := []string{
+		"IF outlook = rain AND wind = weak THEN true",
+		"IF outlook = rain AND wind = strong THEN false",
+		"IF outlook = overcast THEN true",
+		"IF outlook = sunny AND humidity = 0 THEN true",
+		"IF outlook = sunny AND humidity = 1 THEN false",
+	}
+	if rules := dtree.Rules(labels); !reflect.DeepEqual(rules, expectedRules) {
+		t.Error("Expected", expectedRules, "got", rules)
+	}
+	if rules := dtree.Rules(); rules[2] != "IF outlook = 1 THEN true" {
+		t.Error("Expected numeric values without a decoder, got", rules)
+	}
+
+	thresholded := &Decision{featureName: "age", continuous: true, threshold: 61, nextDecisions: map[Feature]*Decision{
+		0: {isOutput: true, outputValue: "young"},
+		1: {isOutput: true, outputValue: "old"},
+	}}
+	if rules := thresholded.Rules(); !reflect.DeepEqual(rules, []string{"IF age <= 61 THEN young", "IF age > 61 THEN old"}) {
+		t.Error("Expected threshold comparisons, got", rules)
+	}
+	if rules := (&Decision{isOutput: true, outputValue: true}).Rules(); !reflect.DeepEqual(rules, []string{"THEN true"}) {
+		t.Error("Expected an unconditional rule for a lone leaf, got", rules)
+	}
+}
+
+func TestRankedRules(t *testing.T) {
+	ds := tennisDataSet()
+	dtree, err := Train(ds, BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ranked := dtree.RankedRules(ds)
+	if len(ranked) != 5 {
+		t.Fatal("Expected a rule per leaf, got", ranked)
+	}
+	// Overcast days cover 4 of the 14 instances, all of them yes
+	expectedFirst := RankedRule{Rule{[]FeatureStep{{"outlook", 1, EqualTo}}, true}, 4.0 / 14, 1}
+	if !reflect.DeepEqual(ranked[0], expectedFirst) {
+		t.Error("Expected", expectedFirst, "got", ranked[0])
+	}
+	for i := 1; i < len(ranked); i++ {
+		if ranked[i].Coverage > ranked[i-1].Coverage {
+			t.Error("Expected rules in descending coverage, got", ranked)
+		}
+	}
+
+	heldOut := ClassifiedDataSet{[]*Instance{{map[string]Feature{"outlook": 1, "humidity": 0, "wind": 0}, false, 1}}}
+	if ranked := dtree.RankedRules(heldOut); ranked[0].Coverage != 1 || ranked[0].Accuracy != 0 {
+		t.Error("Expected the overcast rule to cover the instance and get it wrong, got", ranked[0])
+	}
+}
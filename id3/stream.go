@@ -0,0 +1,32 @@
+package id3
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+)
+
+// Trains like Train on a stream of instances too large to hold in memory, reading ch until it is closed. A uniform
+// random sample of up to maxInstances of the instances is kept by reservoir sampling, so memory stays bounded
+// however long the stream is, and the tree is trained on the sample. The same seed and stream always give the same
+// sample. Errors without reading ch if maxInstances is less than 1.
+func TrainFromChannel(ch <-chan *Instance, bf BestFeatureFunc, maxInstances int, seed int64) (*Decision, error) {
+	if maxInstances < 1 {
+		return nil, errors.New(fmt.Sprint("need room for at least one instance, got ", maxInstances))
+	}
+	return Train(ClassifiedDataSet{Instances: reservoirSample(ch, maxInstances, rand.New(rand.NewSource(seed)))}, bf)
+}
+
+// Reads ch until it is closed, keeping a uniform random sample of up to size of its instances.
+func reservoirSample(ch <-chan *Instance, size int, rng *rand.Rand) []*Instance {
+	sample := make([]*Instance, 0, size)
+	seen := 0
+	for inst := range ch {
+		if seen++; len(sample) < size {
+			sample = append(sample, inst)
+		} else if i := rng.Intn(seen); i < size { // Replaces a kept instance with probability size/seen
+			sample[i] = inst
+		}
+	}
+	return sample
+}
@@ -0,0 +1,74 @@
+package id3
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// Sends the instances on a channel, closing it after the last.
+func streamInstances(insts []*Instance) <-chan *Instance {
+	ch := make(chan *Instance)
+	go func() {
+		for _, inst := range insts {
+			ch <- inst
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+func TestTrainFromChannel(t *testing.T) {
+	// A reservoir as large as the stream keeps every instance, so gives the same tree as training on them all
+	dtree, err := TrainFromChannel(streamInstances(tennisDataSet().Instances), BestFeatureInformationGain, 14, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedTree, _ := Train(tennisDataSet(), BestFeatureInformationGain)
+	if !dtree.Equal(expectedTree) {
+		t.Error("Expected", expectedTree.String(), "got", dtree.String())
+	}
+
+	ds := randomBinaryDataSet(5000, 6, 1)
+	first, err := TrainFromChannel(streamInstances(ds.Instances), BestFeatureInformationGain, 500, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := TrainFromChannel(streamInstances(ds.Instances), BestFeatureInformationGain, 500, 7)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(first, second) {
+		t.Error("Expected the same seed to give the same tree")
+	}
+	if support := first.Stats().Support; support != 500 {
+		t.Error("Expected a sample of 500 instances, got", support)
+	}
+
+	if _, err := TrainFromChannel(streamInstances(nil), BestFeatureInformationGain, 10, 1); err == nil {
+		t.Error("Expected an error training on an empty stream")
+	}
+	if _, err := TrainFromChannel(nil, BestFeatureInformationGain, 0, 1); err == nil {
+		t.Error("Expected an error with no room for instances")
+	}
+}
+
+func TestReservoirSample(t *testing.T) {
+	// Every instance should be kept about as often as every other
+	insts := make([]*Instance, 10)
+	for i := range insts {
+		insts[i] = &Instance{map[string]Feature{"i": Feature(i)}, nil, 1}
+	}
+	kept := make([]int, len(insts))
+	rng := rand.New(rand.NewSource(1))
+	for trial := 0; trial < 2000; trial++ {
+		for _, inst := range reservoirSample(streamInstances(insts), 3, rng) {
+			kept[inst.FeatureValues["i"]]++
+		}
+	}
+	for i, count := range kept {
+		if math.Abs(float64(count)-600) > 90 {
+			t.Error("Expected instance", i, "to be kept about 600 times, got", count)
+		}
+	}
+}
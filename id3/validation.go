@@ -0,0 +1,250 @@
+package id3
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Runs k-fold cross-validation, training on all folds but one and reporting the error on the held out fold, once
+// for each fold. The instances are shuffled with seed and dealt into folds whose sizes differ by at most one.
+func CrossValidate(ds ClassifiedDataSet, bf BestFeatureFunc, k int, seed int64) ([]float64, error) {
+	if k < 2 || k > len(ds.Instances) {
+		return nil, errors.New(fmt.Sprint("cannot make ", k, " folds from ", len(ds.Instances), " instances"))
+	}
+	folds := make([][]*Instance, k)
+	for i, inst := range shuffle(ds.Instances, rand.New(rand.NewSource(seed))) {
+		folds[i%k] = append(folds[i%k], inst)
+	}
+	return crossValidateFolds(folds, bf)
+}
+
+// Runs stratified k-fold cross-validation repeats times, reshuffling the folds each time, and reports the mean
+// error over every repeat along with the sample standard deviation of the repeats' errors. That std is how far a
+// single k-fold run's error can land from the mean just by how the folds fell, so it stays put as repeats are
+// added, unlike the standard error of the mean, which shrinks towards 0. A single repeat has no spread, so its std
+// is 0.
+func RepeatedCrossValidate(ds ClassifiedDataSet, bf BestFeatureFunc, k, repeats int, seed int64) (mean, std float64, err error) {
+	if k < 2 || k > len(ds.Instances) {
+		return 0, 0, errors.New(fmt.Sprint("cannot make ", k, " folds from ", len(ds.Instances), " instances"))
+	} else if repeats < 1 {
+		return 0, 0, errors.New(fmt.Sprint("need at least one repeat, got ", repeats))
+	}
+	rng := rand.New(rand.NewSource(seed))
+	repeatMeans := make([]float64, repeats)
+	for r := range repeatMeans {
+		foldErrors, err := crossValidateFolds(stratifiedFolds(ds.Instances, k, rng), bf)
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, foldError := range foldErrors {
+			repeatMeans[r] += foldError / float64(k)
+		}
+		mean += repeatMeans[r] / float64(repeats)
+	}
+	if repeats > 1 {
+		for _, repeatMean := range repeatMeans {
+			std += (repeatMean - mean) * (repeatMean - mean)
+		}
+		std = math.Sqrt(std / float64(repeats-1))
+	}
+	return mean, std, nil
+}
+
+// Estimates how much the tree's error on a dataset depends on the particular instances in it, by computing the error
+// on resamples bootstrap resamples of the dataset drawn with a RNG seeded with seed. Reports the mean error over the
+// resamples and a 95% percentile interval, so the error can be given as a range rather than a single number.
+func BootstrapError(dtree *Decision, ds ClassifiedDataSet, resamples int, seed int64) (mean, lo, hi float64, err error) {
+	if len(ds.Instances) == 0 {
+		return 0, 0, 0, errors.New("no instances provided")
+	} else if resamples < 1 {
+		return 0, 0, 0, errors.New(fmt.Sprint("need at least one resample, got ", resamples))
+	}
+	rng := rand.New(rand.NewSource(seed))
+	errorRates := make([]float64, resamples)
+	for i := range errorRates {
+		sample, _ := bootstrapSample(ds.Instances, rng)
+		if errorRates[i], err = dtree.CalculateError(ClassifiedDataSet{Instances: sample}); err != nil {
+			return 0, 0, 0, err
+		}
+		mean += errorRates[i] / float64(resamples)
+	}
+	sort.Float64s(errorRates)
+	last := float64(resamples - 1)
+	lo = errorRates[int(clamp(math.Floor(0.025*float64(resamples)), 0, last))]
+	hi = errorRates[int(clamp(math.Ceil(0.975*float64(resamples))-1, 0, last))]
+	return mean, lo, hi, nil
+}
+
+// Finds a small set of features whose tree reaches targetFraction of the validation accuracy of a tree trained on
+// every feature. Features are added greedily, each time picking the one that most improves validation accuracy.
+func MinimalFeatureSet(train, validate ClassifiedDataSet, bf BestFeatureFunc, targetFraction float64) ([]string, error) {
+	if len(train.Instances) == 0 {
+		return nil, errors.New("no instances provided")
+	}
+	fullTree, err := Train(train, bf)
+	if err != nil {
+		return nil, err
+	}
+	fullError, err := fullTree.CalculateError(validate)
+	if err != nil {
+		return nil, err
+	}
+	targetAccuracy := targetFraction * (1 - fullError)
+
+	// Sorted so ties in accuracy go to the lexicographically smallest feature
+	var remaining []string
+	for featureName := range featureSchema(train) {
+		remaining = append(remaining, featureName)
+	}
+	sort.Strings(remaining)
+
+	selected := make([]string, 0, len(remaining))
+	for len(remaining) > 0 {
+		bestAccuracy, bestIndex := -1.0, 0
+		for i, featureName := range remaining {
+			dtree, err := Train(withFeatures(train, append(selected, featureName)), bf)
+			if err != nil {
+				return nil, err
+			}
+			candidateError, err := dtree.CalculateError(validate)
+			if err != nil {
+				return nil, err
+			}
+			if 1-candidateError > bestAccuracy {
+				bestAccuracy, bestIndex = 1-candidateError, i
+			}
+		}
+		selected = append(selected, remaining[bestIndex])
+		remaining = append(remaining[:bestIndex], remaining[bestIndex+1:]...)
+		if bestAccuracy >= targetAccuracy {
+			break
+		}
+	}
+	return selected, nil
+}
+
+// Sweeps the maximum depth from 1 to maxDepth, returning the tree with the lowest validation error and its depth
+// bound. Ties go to the shallower tree.
+func TrainBestDepth(train, validate ClassifiedDataSet, bf BestFeatureFunc, maxDepth int) (*Decision, int, error) {
+	if maxDepth < 1 {
+		return nil, 0, errors.New(fmt.Sprint("need a maximum depth of at least 1, got ", maxDepth))
+	}
+	var bestTree *Decision
+	bestDepth, bestError := 0, math.Inf(1)
+	for depth := 1; depth <= maxDepth; depth++ {
+		dtree, err := TrainWithOptions(train, Options{BestFeature: bf, MaxDepth: depth})
+		if err != nil {
+			return nil, 0, err
+		}
+		validateError, err := dtree.CalculateError(validate)
+		if err != nil {
+			return nil, 0, err
+		}
+		if validateError < bestError {
+			bestTree, bestDepth, bestError = dtree, depth, validateError
+		}
+	}
+	return bestTree, bestDepth, nil
+}
+
+// Flags instances that look mislabeled: ones whose held out prediction disagrees with their given target in every
+// one of several repeats of stratified k-fold cross-validation. Instances are returned in dataset order.
+func FindLabelNoise(ds ClassifiedDataSet, bf BestFeatureFunc, k int, seed int64) ([]*Instance, error) {
+	const repeats = 5
+	if k < 2 || k > len(ds.Instances) {
+		return nil, errors.New(fmt.Sprint("cannot make ", k, " folds from ", len(ds.Instances), " instances"))
+	}
+	rng := rand.New(rand.NewSource(seed))
+	disagreements := make(map[*Instance]int, len(ds.Instances))
+	for r := 0; r < repeats; r++ {
+		folds := stratifiedFolds(ds.Instances, k, rng)
+		for i := range folds {
+			var train ClassifiedDataSet
+			for j, fold := range folds {
+				if j != i {
+					train.Instances = append(train.Instances, fold...)
+				}
+			}
+			dtree, err := Train(train, bf)
+			if err != nil {
+				return nil, err
+			}
+			for _, inst := range folds[i] {
+				// An instance the tree can't classify gives no evidence either way
+				if target, err := dtree.Predict(inst); err == nil && target != inst.TargetValue {
+					disagreements[inst]++
+				}
+			}
+		}
+	}
+
+	var noisy []*Instance
+	for _, inst := range ds.Instances {
+		if disagreements[inst] == repeats {
+			noisy = append(noisy, inst)
+		}
+	}
+	return noisy, nil
+}
+
+// Calculates the accuracy a classifier always predicting the most likely target would get, given the prior of each
+// target. Priors that don't sum to 1, like raw counts, are normalized first.
+func ExpectedMajorityAccuracy(priors map[Target]float64) float64 {
+	total, largest := 0.0, 0.0
+	for _, prior := range priors {
+		total += prior
+		largest = math.Max(largest, prior)
+	}
+	if total == 0 {
+		return 0
+	}
+	return largest / total
+}
+
+// Trains on all folds but one and measures the error on the held out fold, once for each fold.
+func crossValidateFolds(folds [][]*Instance, bf BestFeatureFunc) ([]float64, error) {
+	foldErrors := make([]float64, len(folds))
+	for i := range folds {
+		var train ClassifiedDataSet
+		for j, fold := range folds {
+			if j != i {
+				train.Instances = append(train.Instances, fold...)
+			}
+		}
+		dtree, err := Train(train, bf)
+		if err != nil {
+			return nil, err
+		}
+		if foldErrors[i], err = dtree.CalculateError(ClassifiedDataSet{folds[i]}); err != nil {
+			return nil, err
+		}
+	}
+	return foldErrors, nil
+}
+
+// Deals instances into k folds that each keep roughly the target distribution of the whole. Instances are
+// shuffled with rng first, so successive calls produce different partitions.
+func stratifiedFolds(insts []*Instance, k int, rng *rand.Rand) [][]*Instance {
+	byTarget := make(map[Target][]*Instance)
+	var targets []Target // In order of first appearance, keeping the deal deterministic for a given rng
+	for _, i := range rng.Perm(len(insts)) {
+		if _, ok := byTarget[insts[i].TargetValue]; !ok {
+			targets = append(targets, insts[i].TargetValue)
+		}
+		byTarget[insts[i].TargetValue] = append(byTarget[insts[i].TargetValue], insts[i])
+	}
+
+	// Dealing round-robin over the instances grouped by target spreads every target evenly
+	folds := make([][]*Instance, k)
+	dealt := 0
+	for _, target := range targets {
+		for _, inst := range byTarget[target] {
+			folds[dealt%k] = append(folds[dealt%k], inst)
+			dealt++
+		}
+	}
+	return folds
+}
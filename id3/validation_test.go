@@ -0,0 +1,244 @@
+package id3
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// A dataset where "signal" decides the target, except when it is 2 and the target is a coin flip.
+func noisyDataSet(n int) ClassifiedDataSet {
+	ds := ClassifiedDataSet{}
+	for i := 0; i < n; i++ {
+		signal, other := Feature(i%3), Feature(i/3%2)
+		target := Target(signal == 1)
+		if signal == 2 {
+			target = Target(i/6%2 == 0)
+		}
+		ds.Instances = append(ds.Instances, &Instance{map[string]Feature{"signal": signal, "other": other}, target, 1})
+	}
+	return ds
+}
+
+func TestCrossValidate(t *testing.T) {
+	ds := ClassifiedDataSet{}
+	for i := 0; i < 30; i++ {
+		ds.Instances = append(ds.Instances, &Instance{map[string]Feature{"signal": Feature(i % 3)}, Target(i%3 == 1), 1})
+	}
+	foldErrors, err := CrossValidate(ds, BestFeatureInformationGain, 4, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []float64{0, 0, 0, 0}; !reflect.DeepEqual(foldErrors, expected) {
+		t.Error("Expected", expected, "got", foldErrors)
+	}
+	for _, k := range []int{1, 31} {
+		if _, err := CrossValidate(ds, BestFeatureInformationGain, k, 1); err == nil {
+			t.Error("Expected an error making", k, "folds from 30 instances")
+		}
+	}
+}
+
+func TestRepeatedCrossValidate(t *testing.T) {
+	ds := noisyDataSet(60)
+	mean, std, err := RepeatedCrossValidate(ds, BestFeatureInformationGain, 5, 30, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mean <= 0 || mean > 1.0/3 {
+		t.Error("Expected mean error within the coin-flip third of the data, got", mean)
+	}
+
+	// Independent k-fold runs should spread about as much as the repeats do
+	runErrors := make([]float64, 30)
+	runMean := 0.0
+	for i := range runErrors {
+		foldErrors, err := CrossValidate(ds, BestFeatureInformationGain, 5, int64(i+1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, foldError := range foldErrors {
+			runErrors[i] += foldError / 5
+		}
+		runMean += runErrors[i] / 30
+	}
+	runStd := 0.0
+	for _, runError := range runErrors {
+		runStd += (runError - runMean) * (runError - runMean) / 29
+	}
+	runStd = math.Sqrt(runStd)
+	if std < runStd/2 || std > runStd*2 {
+		t.Error("Expected a std near the", runStd, "spread of independent runs, got", std)
+	}
+
+	// A standard error would shrink by a factor of 4 with 16 times the repeats, but the spread of the runs doesn't
+	_, fewStd, err := RepeatedCrossValidate(ds, BestFeatureInformationGain, 5, 8, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, manyStd, err := RepeatedCrossValidate(ds, BestFeatureInformationGain, 5, 128, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manyStd < fewStd/2 {
+		t.Error("Expected the std to hold steady with more repeats, got", fewStd, "then", manyStd)
+	}
+
+	// Every fold of a noise-free dataset is classified perfectly, however the folds fall
+	separable := ClassifiedDataSet{}
+	for i := 0; i < 40; i++ {
+		separable.Instances = append(separable.Instances, &Instance{map[string]Feature{"a": Feature(i % 2)}, Target(i%2 == 0), 1})
+	}
+	if mean, std, err := RepeatedCrossValidate(separable, BestFeatureInformationGain, 5, 10, 1); err != nil || mean != 0 || std != 0 {
+		t.Error("Expected no error and no spread, got", mean, std, err)
+	}
+
+	if _, std, err := RepeatedCrossValidate(ds, BestFeatureInformationGain, 5, 1, 1); err != nil || std != 0 {
+		t.Error("Expected no spread from a single repeat, got", std, err)
+	}
+
+	if _, _, err := RepeatedCrossValidate(ds, BestFeatureInformationGain, 61, 1, 1); err == nil {
+		t.Error("Expected an error with more folds than instances")
+	}
+}
+
+func TestBootstrapError(t *testing.T) {
+	ds := tennisDataSet()
+	perfect, err := Train(ds, BestFeatureInformationGain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mean, lo, hi, err := BootstrapError(perfect, ds, 100, 1); err != nil || mean != 0 || lo != 0 || hi != 0 {
+		t.Error("Expected no error in any resample, got", mean, lo, hi, err)
+	}
+
+	// The stump gets 4 of the 14 wrong
+	stump, err := TrainWithOptions(ds, Options{MaxDepth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mean, lo, hi, err := BootstrapError(stump, ds, 1000, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(mean-4.0/14) > 0.02 || lo >= 4.0/14 || hi <= 4.0/14 || hi-lo > 0.6 {
+		t.Error("Expected a mean near", 4.0/14, "inside an interval around it, got", mean, lo, hi)
+	}
+	if againMean, againLo, againHi, _ := BootstrapError(stump, ds, 1000, 1); againMean != mean || againLo != lo || againHi != hi {
+		t.Error("Expected the same estimate for the same seed")
+	}
+
+	if _, _, _, err := BootstrapError(stump, ds, 0, 1); err == nil {
+		t.Error("Expected an error with no resamples")
+	}
+	if _, _, _, err := BootstrapError(stump, ClassifiedDataSet{}, 10, 1); err == nil {
+		t.Error("Expected an error with no instances")
+	}
+	unseen := ClassifiedDataSet{[]*Instance{{map[string]Feature{"outlook": 7}, true, 1}}}
+	if _, _, _, err := BootstrapError(stump, unseen, 10, 1); err == nil {
+		t.Error("Expected an error for an instance the tree can't classify")
+	}
+}
+
+func TestMinimalFeatureSet(t *testing.T) {
+	ds := tennisDataSet()
+	featureNames, err := MinimalFeatureSet(ds, ds, BestFeatureInformationGain, 0.8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// humidity and outlook each reach 10/14 alone; together they reach at least 12/14
+	if !reflect.DeepEqual(featureNames, []string{"humidity", "outlook"}) {
+		t.Error("Expected [humidity outlook], got", featureNames)
+	}
+
+	featureNames, err = MinimalFeatureSet(ds, ds, BestFeatureInformationGain, 1)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(featureNames) != 3 {
+		t.Error("Expected outlook, humidity and wind for a perfect fit, got", featureNames)
+	}
+}
+
+func TestTrainBestDepth(t *testing.T) {
+	// Only "signal" matters; deeper trees fit the flipped labels through the noise features
+	noisy := func(n int, seed int64) ClassifiedDataSet {
+		rng := rand.New(rand.NewSource(seed))
+		ds := ClassifiedDataSet{}
+		for i := 0; i < n; i++ {
+			inst := &Instance{FeatureValues: map[string]Feature{"signal": Feature(rng.Intn(2))}}
+			for _, featureName := range []string{"n1", "n2", "n3", "n4"} {
+				inst.FeatureValues[featureName] = Feature(rng.Intn(2))
+			}
+			inst.TargetValue = Target(inst.FeatureValues["signal"] == 1) != Target(rng.Float64() < 0.2)
+			ds.Instances = append(ds.Instances, inst)
+		}
+		return ds
+	}
+	train, validate := noisy(60, 1), noisy(1000, 2)
+
+	dtree, depth, err := TrainBestDepth(train, validate, BestFeatureInformationGain, 5)
+	if err != nil {
+		t.Fatal(err)
+	} else if depth >= 5 {
+		t.Error("Expected a shallower tree to validate best, got depth", depth)
+	}
+	deepest, _ := TrainWithOptions(train, Options{MaxDepth: 5})
+	bestError, _ := dtree.CalculateError(validate)
+	deepestError, _ := deepest.CalculateError(validate)
+	if bestError >= deepestError {
+		t.Error("Expected the chosen tree to beat the deepest, got", bestError, "and", deepestError)
+	}
+}
+
+func TestFindLabelNoise(t *testing.T) {
+	ds := ClassifiedDataSet{}
+	for i := 0; i < 60; i++ {
+		signal, other := Feature(i%2), Feature(i/2%3)
+		ds.Instances = append(ds.Instances, &Instance{map[string]Feature{"signal": signal, "other": other}, Target(signal == 1), 1})
+	}
+	var flipped []*Instance
+	for _, i := range []int{7, 20, 43} {
+		ds.Instances[i].TargetValue = Target(ds.Instances[i].FeatureValues["signal"] == 0)
+		flipped = append(flipped, ds.Instances[i])
+	}
+
+	noisy, err := FindLabelNoise(ds, BestFeatureInformationGain, 5, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(noisy, flipped) {
+		t.Error("Expected the flipped instances", flipped, "got", noisy)
+	}
+	if _, err := FindLabelNoise(ds, BestFeatureInformationGain, 1, 1); err == nil {
+		t.Error("Expected an error with a single fold")
+	}
+}
+
+func TestExpectedMajorityAccuracy(t *testing.T) {
+	if accuracy := ExpectedMajorityAccuracy(map[Target]float64{true: 0.7, false: 0.3}); accuracy != 0.7 {
+		t.Error("Expected 0.7, got", accuracy)
+	}
+	if accuracy := ExpectedMajorityAccuracy(map[Target]float64{true: 9, false: 5}); accuracy != 9.0/14 {
+		t.Error("Expected counts to be normalized to", 9.0/14, "got", accuracy)
+	}
+	if accuracy := ExpectedMajorityAccuracy(nil); accuracy != 0 {
+		t.Error("Expected 0 without priors, got", accuracy)
+	}
+}
+
+func TestStratifiedFolds(t *testing.T) {
+	ds := noisyDataSet(60)
+	folds := stratifiedFolds(ds.Instances, 4, rand.New(rand.NewSource(1)))
+	for _, fold := range folds {
+		positives := 0
+		for _, inst := range fold {
+			if inst.TargetValue == Target(true) {
+				positives++
+			}
+		}
+		if len(fold) != 15 || positives != 7 && positives != 8 { // 30 of the 60 instances are positive
+			t.Error("Expected 15 instances with 7 or 8 positive, got", len(fold), "with", positives)
+		}
+	}
+}